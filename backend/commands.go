@@ -0,0 +1,96 @@
+package main
+
+import (
+	"PRODUCT_LIST/config"
+	"PRODUCT_LIST/domain/repositories"
+	"PRODUCT_LIST/services"
+	"PRODUCT_LIST/utils"
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// runImageGC implements the "gc" subcommand:
+//
+//	run-app gc [-dry-run] [-grace 24h]
+//
+// It runs a single garbage collection pass and prints the report as JSON.
+func runImageGC(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", cfg.ImageGCDryRun, "report orphaned images without deleting them")
+	grace := fs.Duration("grace", cfg.ImageGCGracePeriod, "minimum age of an orphaned image before it is deleted")
+	fs.Parse(args)
+
+	db := openDatabase(cfg)
+	defer db.Close()
+
+	// Garbage collection must see every reference, no replicas
+	productRepo := repositories.NewProductRepository(db, nil, slog.Default())
+	imageRepo := repositories.NewImageRepository(db, slog.Default())
+	imageGC := services.NewImageGCService(productRepo, imageRepo, utils.UploadDir, *grace, slog.Default())
+
+	report, err := imageGC.Run(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatal("Error running image GC:", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
+
+// runImageMigration implements the "migrate-images" subcommand:
+//
+//	run-app migrate-images [-dry-run]
+//
+// It moves legacy product-<nanos> uploads to content-addressed names and
+// prints the report as JSON.
+func runImageMigration(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("migrate-images", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "report the renames without changing files or products")
+	fs.Parse(args)
+
+	db := openDatabase(cfg)
+	defer db.Close()
+
+	imageRepo := repositories.NewImageRepository(db, slog.Default())
+	migration := services.NewImageMigrationService(imageRepo, utils.UploadDir, slog.Default())
+
+	report, err := migration.Run(*dryRun)
+	if err != nil {
+		log.Fatal("Error migrating images:", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
+
+// runSetRole implements the "set-role" subcommand, used to bootstrap the
+// first administrator:
+//
+//	run-app set-role -email admin@example.com -role admin
+func runSetRole(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("set-role", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user")
+	role := fs.String("role", "", "viewer, editor or admin")
+	fs.Parse(args)
+
+	db := openDatabase(cfg)
+	defer db.Close()
+
+	authService := services.NewAuthService(repositories.NewUserRepository(db, slog.Default()), utils.AccessTokenOptions{}, 0, slog.Default())
+
+	user, err := authService.GetUserByEmail(*email)
+	if err != nil {
+		log.Fatal("Error finding user:", err)
+	}
+	if err := authService.SetRole(user.ID, *role); err != nil {
+		log.Fatal("Error setting role:", err)
+	}
+
+	slog.Info("role set", "email", user.Email, "role", *role)
+}
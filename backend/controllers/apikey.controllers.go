@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/services"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type APIKeyController struct {
+	service *services.APIKeyService
+}
+
+func NewAPIKeyController(service *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{service: service}
+}
+
+func (c *APIKeyController) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	createdBy := ""
+	if principal := models.PrincipalFromContext(r.Context()); principal != nil {
+		createdBy = principal.Subject
+	}
+
+	key, err := c.service.Create(req.Name, req.Scopes, req.ExpiresAt, createdBy)
+	if err != nil {
+		if strings.Contains(err.Error(), "scope") || strings.Contains(err.Error(), "name") || strings.Contains(err.Error(), "expiry") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error creating API key", http.StatusInternalServerError)
+		return
+	}
+
+	// The plain key is only ever returned here
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+func (c *APIKeyController) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := c.service.List()
+	if err != nil {
+		http.Error(w, "Error listing API keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+func (c *APIKeyController) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.Revoke(id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error revoking API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": fmt.Sprintf("API key with ID %d successfully revoked", id),
+	})
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type AuthController struct {
+	service *services.AuthService
+}
+
+func NewAuthController(service *services.AuthService) *AuthController {
+	return &AuthController{service: service}
+}
+
+type credentialsRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := c.service.Register(req.Email, req.Password)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "already exists"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case strings.Contains(err.Error(), "required"), strings.Contains(err.Error(), "at least"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Error registering user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := c.service.Login(req.Email, req.Password)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+func (c *AuthController) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "refreshToken is required", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := c.service.Refresh(req.RefreshToken)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+func (c *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "refreshToken is required", http.StatusBadRequest)
+		return
+	}
+
+	// Logging out with an unknown or already revoked token is not an error
+	if err := c.service.Logout(req.RefreshToken); err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+		http.Error(w, "Error logging out", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeTokens(w http.ResponseWriter, tokens *models.TokenPair) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials), errors.Is(err, services.ErrInvalidRefreshToken):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	default:
+		http.Error(w, "Error issuing tokens", http.StatusInternalServerError)
+	}
+}
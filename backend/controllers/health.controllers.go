@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/services"
+	"encoding/json"
+	"net/http"
+)
+
+type HealthController struct {
+	service *services.HealthService
+}
+
+func NewHealthController(service *services.HealthService) *HealthController {
+	return &HealthController{service: service}
+}
+
+// Liveness reports that the process is up and serving requests. It never
+// touches dependencies, a database outage must not get the process restarted.
+func (c *HealthController) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, map[string]string{"status": models.HealthStatusOK})
+}
+
+// Readiness reports whether the instance should receive traffic, with the
+// result of each check. Failing checks answer 503.
+func (c *HealthController) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := c.service.Ready(r.Context())
+
+	status := http.StatusOK
+	if report.Status != models.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, report)
+}
+
+func writeHealth(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
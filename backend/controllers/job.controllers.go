@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/services"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type JobController struct {
+	service *services.JobService
+}
+
+func NewJobController(service *services.JobService) *JobController {
+	return &JobController{service: service}
+}
+
+// ListJobs lists background jobs, newest first, filtered by status and type.
+func (c *JobController) ListJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := models.JobFilter{
+		Status: query.Get("status"),
+		Type:   query.Get("type"),
+	}
+	filter.Page, _ = strconv.Atoi(query.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(query.Get("pageSize"))
+
+	jobs, err := c.service.List(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+func (c *JobController) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := c.service.Get(r.Context(), id)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// RetryJob sends a dead job back to the queue.
+func (c *JobController) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := c.service.Retry(r.Context(), id)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func writeJobError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
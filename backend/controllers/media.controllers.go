@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"PRODUCT_LIST/services"
+	"PRODUCT_LIST/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// MediaController serves uploaded product images. It replaces the plain
+// http.FileServer: directories are never listed and images of private
+// products are only served with a valid signature.
+type MediaController struct {
+	service    *services.ProductService
+	transforms *services.ImageTransformService
+	signer     *utils.URLSigner
+	dir        string
+}
+
+func NewMediaController(service *services.ProductService, transforms *services.ImageTransformService, signer *utils.URLSigner, dir string) *MediaController {
+	return &MediaController{service: service, transforms: transforms, signer: signer, dir: dir}
+}
+
+// authorize checks that the image stored under name may be served for this
+// request and reports whether it belongs to a private product.
+func (c *MediaController) authorize(w http.ResponseWriter, r *http.Request, name string) (private bool, ok bool) {
+	imagePath := "/uploads/" + name
+	private, err := c.service.IsPrivateImage(r.Context(), imagePath)
+	if err != nil {
+		http.Error(w, "Error loading image", http.StatusInternalServerError)
+		return false, false
+	}
+
+	query := r.URL.Query()
+	if private && !c.signer.Verify(imagePath, query.Get("expires"), query.Get("signature")) {
+		http.Error(w, "Invalid or expired signature", http.StatusForbidden)
+		return true, false
+	}
+
+	return private, true
+}
+
+var imageContentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+}
+
+func setImageCacheHeaders(w http.ResponseWriter, name string, private bool) {
+	switch {
+	case private:
+		w.Header().Set("Cache-Control", "private, no-store")
+	case utils.IsContentAddressed(name):
+		// The name is the hash of the content, it can be cached forever
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+}
+
+func (c *MediaController) ServeUpload(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/uploads/")
+	// Hidden files are uploads still being written
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\") {
+		http.NotFound(w, r)
+		return
+	}
+
+	private, ok := c.authorize(w, r, name)
+	if !ok {
+		return
+	}
+
+	file, err := os.Open(filepath.Join(c.dir, name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Never let the browser guess the type of an uploaded file, anything
+	// that is not a known image is only offered as a download
+	if contentType, ok := imageContentTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		w.Header().Set("Content-Type", contentType)
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment")
+	}
+
+	setImageCacheHeaders(w, name, private)
+	if utils.IsContentAddressed(name) {
+		w.Header().Set("ETag", `"`+strings.TrimSuffix(name, filepath.Ext(name))+`"`)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// GetImage serves a rendition of a stored image:
+//
+//	GET /api/images/{key}?preset=thumb
+//	GET /api/images/{key}?w=400&h=300&fit=cover&format=jpeg
+//
+// Only the sizes of the configured presets are accepted. Without any
+// parameter the original image is returned.
+func (c *MediaController) GetImage(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	query := r.URL.Query()
+
+	width, _ := strconv.Atoi(query.Get("w"))
+	height, _ := strconv.Atoi(query.Get("h"))
+
+	preset, err := c.transforms.ResolvePreset(query.Get("preset"), width, height, query.Get("fit"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	private, ok := c.authorize(w, r, key)
+	if !ok {
+		return
+	}
+
+	file, contentType, err := c.transforms.Render(key, preset, query.Get("format"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrImageNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, services.ErrUnsupportedFormat):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrImageDimensions):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, "Error processing image", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Error processing image", http.StatusInternalServerError)
+		return
+	}
+
+	setImageCacheHeaders(w, key, private)
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+func (c *MediaController) GetImagePresets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.transforms.Presets())
+}
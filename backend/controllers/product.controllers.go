@@ -3,22 +3,58 @@ package controllers
 import (
 	"PRODUCT_LIST/domain/models"
 	"PRODUCT_LIST/services"
+	"PRODUCT_LIST/tracing"
 	"PRODUCT_LIST/utils"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
 )
 
+// PermissionChecker reports whether the caller of a request holds a
+// permission beyond the one its route requires.
+type PermissionChecker interface {
+	Allowed(ctx context.Context, permission string) bool
+}
+
 type ProductController struct {
-	service *services.ProductService
+	service     *services.ProductService
+	signer      *utils.URLSigner
+	quotas      *services.UploadQuotaService
+	permissions PermissionChecker
+	logger      *slog.Logger
+}
+
+func NewProductController(service *services.ProductService, signer *utils.URLSigner, quotas *services.UploadQuotaService, permissions PermissionChecker, logger *slog.Logger) *ProductController {
+	return &ProductController{service: service, signer: signer, quotas: quotas, permissions: permissions, logger: logger}
+}
+
+// canReadPrivate reports whether private products are shown to the caller.
+func (c *ProductController) canReadPrivate(r *http.Request) bool {
+	return c.permissions.Allowed(r.Context(), models.PermissionProductsReadPrivate)
 }
 
-func NewProductController(service *services.ProductService) *ProductController {
-	return &ProductController{service: service}
+// signImages replaces the image paths of private products with expiring
+// signed URLs before products are returned to clients.
+func (c *ProductController) signImages(products []models.Product) {
+	for i := range products {
+		signImage(c.signer, &products[i])
+	}
+}
+
+// signImage signs the image URL of a private product. Public images keep
+// their stable URL, so that it can be cached as immutable.
+func signImage(signer *utils.URLSigner, product *models.Product) {
+	if product.Private {
+		product.ImageURL = signer.Sign(product.ImageURL)
+	}
 }
 
 func (c *ProductController) GetProducts(w http.ResponseWriter, r *http.Request) {
@@ -35,12 +71,14 @@ func (c *ProductController) GetProducts(w http.ResponseWriter, r *http.Request)
 		pageSize = 5 // default page size (change to 10 later)
 	}
 
-	products, err := c.service.GetProducts(page, pageSize)
+	products, err := c.service.GetProducts(r.Context(), page, pageSize, c.canReadPrivate(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	c.signImages(products)
+
 	response := struct {
 		Products []models.Product `json:"products"`
 		Page     int              `json:"page"`
@@ -63,11 +101,16 @@ func (c *ProductController) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := c.service.GetProduct(id)
+	product, err := c.service.GetProduct(r.Context(), id)
+	if err == nil && product.Private && !c.canReadPrivate(r) {
+		// Private products do not exist for callers who may not see them
+		err = fmt.Errorf("product with ID %d not found", id)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	signImage(c.signer, product)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
@@ -93,6 +136,10 @@ func (c *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request
 	// Parse multipart form
 	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
+		if isBodyTooLarge(err) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Error parsing form data: "+err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -101,6 +148,7 @@ func (c *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request
 	product.Name = r.FormValue("name")
 	product.Type = r.FormValue("type")
 	product.Description = r.FormValue("description")
+	product.Private, _ = strconv.ParseBool(r.FormValue("private"))
 
 	// Parse price with proper error handling
 	priceStr := r.FormValue("price")
@@ -118,18 +166,23 @@ func (c *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	var image io.Reader
+	var ext string
 	if file != nil {
 		defer file.Close()
-		imageURL, err := utils.HandleFileUpload(file, handler)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if !utils.IsAllowedFileType(handler.Filename) {
+			http.Error(w, "invalid file type. Only jpg, jpeg, png allowed", http.StatusBadRequest)
+			return
+		}
+		if err := c.quotas.Reserve(r.Context(), handler.Size); err != nil {
+			writeQuotaError(w, err, c.quotas)
 			return
 		}
-		product.ImageURL = imageURL
+		image, ext = file, filepath.Ext(handler.Filename)
 	}
 
-	// Call service to create product
-	err = c.service.Create(&product)
+	// Call service to create product, the image is stored in the same unit of work
+	err = c.service.CreateWithImage(r.Context(), &product, image, ext)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -137,6 +190,7 @@ func (c *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request
 	// Add this to your Go handler
 	// fmt.Printf("Received file: %+v\n", handler.Filename)
 	// Return response
+	signImage(c.signer, &product)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(product)
 }
@@ -179,10 +233,6 @@ func (c *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request
 // }
 
 func (c *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request) {
-	// Log request details
-	// log.Printf("Update Request - Method: %s, URL: %s", r.Method, r.URL.Path)
-	// log.Printf("Content-Type: %s", r.Header.Get("Content-Type"))
-
 	// // Get id from URL parameter
 	// vars := mux.Vars(r)
 	// id, err := strconv.Atoi(vars["id"])
@@ -191,9 +241,6 @@ func (c *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request
 	// 	return
 	// }
 
-	// // Log form data
-	// log.Printf("Form data: %+v", r.Form)
-
 	// // Parse request body
 	// var product models.Product
 	// err = json.NewDecoder(r.Body).Decode(&product)
@@ -238,6 +285,10 @@ func (c *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request
 	// Parse multipart form
 	err = r.ParseMultipartForm(10 << 20)
 	if err != nil {
+		if isBodyTooLarge(err) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Error parsing form data: "+err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -249,6 +300,7 @@ func (c *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request
 	product.Name = r.FormValue("name")
 	product.Type = r.FormValue("type")
 	product.Description = r.FormValue("description")
+	product.Private, _ = strconv.ParseBool(r.FormValue("private"))
 
 	// Parse price
 	priceStr := r.FormValue("price")
@@ -266,21 +318,27 @@ func (c *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	var image io.Reader
+	var ext string
 	if file != nil {
 		defer file.Close()
-		imageURL, err := utils.HandleFileUpload(file, handler)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if !utils.IsAllowedFileType(handler.Filename) {
+			http.Error(w, "invalid file type. Only jpg, jpeg, png allowed", http.StatusBadRequest)
 			return
 		}
-		product.ImageURL = imageURL
+		if err := c.quotas.Reserve(r.Context(), handler.Size); err != nil {
+			writeQuotaError(w, err, c.quotas)
+			return
+		}
+		image, ext = file, filepath.Ext(handler.Filename)
 	} else {
-		// Keep existing image URL if no new file is uploaded
-		product.ImageURL = r.FormValue("image_url")
+		// Keep existing image URL if no new file is uploaded.
+		// Clients may send back the signed URL they received, only the path is stored.
+		product.ImageURL = stripQuery(r.FormValue("image_url"))
 	}
 
 	// Call service to update
-	err = c.service.UpdateProduct(&product)
+	err = c.service.UpdateWithImage(r.Context(), &product, image, ext)
 	if err != nil {
 		switch {
 		case err.Error() == "name cannot be empty":
@@ -292,6 +350,7 @@ func (c *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request
 		case strings.Contains(err.Error(), "not found"):
 			http.Error(w, err.Error(), http.StatusNotFound)
 		default:
+			c.logger.ErrorContext(r.Context(), "updating product failed", "product_id", id, "error", err)
 			http.Error(w, "Error updating product", http.StatusInternalServerError)
 		}
 		return
@@ -314,7 +373,7 @@ func (c *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Request
 	}
 
 	// Call the service method to delete
-	err = c.service.DeleteProduct(id)
+	err = c.service.DeleteProduct(r.Context(), id)
 	if err != nil {
 		// Check if it's a "not found" error
 		if err.Error() == "product not found" {
@@ -322,6 +381,7 @@ func (c *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Request
 			return
 		}
 		// For other errors
+		c.logger.ErrorContext(r.Context(), "deleting product failed", "product_id", id, "error", err)
 		http.Error(w, "Error deleting product", http.StatusInternalServerError)
 		return
 	}
@@ -333,6 +393,34 @@ func (c *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// ImportProducts creates the products of a JSON array, all of them or none.
+func (c *ProductController) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	var products []models.Product
+	if err := json.NewDecoder(r.Body).Decode(&products); err != nil {
+		if isBodyTooLarge(err) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.ImportProducts(r.Context(), products); err != nil {
+		msg := err.Error()
+		if strings.Contains(msg, "invalid") || strings.Contains(msg, "required") {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		c.logger.ErrorContext(r.Context(), "importing products failed", "error", err)
+		http.Error(w, "Error importing products", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(products)
+}
+
 func (c *ProductController) SearchProducts(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	if name == "" {
@@ -340,11 +428,12 @@ func (c *ProductController) SearchProducts(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	products, err := c.service.SearchProducts(name)
+	products, err := c.service.SearchProducts(r.Context(), name, c.canReadPrivate(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	c.signImages(products)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(products)
@@ -389,13 +478,22 @@ func (c *ProductController) GetPagedProducts(w http.ResponseWriter, r *http.Requ
 	params.Type = r.URL.Query().Get("type")
 	params.SortBy = r.URL.Query().Get("sortBy")
 	params.SortOrder = r.URL.Query().Get("sortOrder")
+	params.IncludePrivate = c.canReadPrivate(r)
 
-	response, err := c.service.GetPagedProducts(params)
+	response, err := c.service.GetPagedProducts(r.Context(), params)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	c.signImages(response.Products)
 
 	w.Header().Set("Content-Type", "application/json")
+	_, span := tracing.Start(r.Context(), "encode products")
 	json.NewEncoder(w).Encode(response)
+	span.End()
+}
+
+func stripQuery(imageURL string) string {
+	path, _, _ := strings.Cut(imageURL, "?")
+	return path
 }
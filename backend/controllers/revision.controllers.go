@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"PRODUCT_LIST/services"
+	"PRODUCT_LIST/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type RevisionController struct {
+	service *services.RevisionService
+	signer  *utils.URLSigner
+}
+
+func NewRevisionController(service *services.RevisionService, signer *utils.URLSigner) *RevisionController {
+	return &RevisionController{service: service, signer: signer}
+}
+
+func (c *RevisionController) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := c.service.List(r.Context(), id)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	for i := range revisions {
+		revisions[i].Product.ImageURL = c.signer.Sign(revisions[i].Product.ImageURL)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+func (c *RevisionController) GetRevision(w http.ResponseWriter, r *http.Request) {
+	id, revision, ok := parseRevisionVars(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := c.service.Get(r.Context(), id, revision)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	result.Product.ImageURL = c.signer.Sign(result.Product.ImageURL)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DiffRevisions compares the revisions given by the from and to query
+// parameters.
+func (c *RevisionController) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "from must be a revision number", http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "to must be a revision number", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := c.service.Diff(r.Context(), id, from, to)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+func (c *RevisionController) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	id, revision, ok := parseRevisionVars(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := c.service.Restore(r.Context(), id, revision)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	product.ImageURL = c.signer.Sign(product.ImageURL)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func parseRevisionVars(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	revision, err := strconv.Atoi(vars["rev"])
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return id, revision, true
+}
+
+func writeRevisionError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Error processing product revision", http.StatusInternalServerError)
+}
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"PRODUCT_LIST/services"
+	"PRODUCT_LIST/utils"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const tusVersion = "1.0.0"
+
+// UploadController exposes resumable uploads following the tus protocol:
+//
+//	POST   /api/uploads                create, Upload-Length + Upload-Metadata headers
+//	HEAD   /api/uploads/{id}           current Upload-Offset
+//	PATCH  /api/uploads/{id}           append a chunk at Upload-Offset
+//	DELETE /api/uploads/{id}           abort
+//	POST   /api/uploads/{id}/finalize  attach the finished file to a product
+type UploadController struct {
+	service *services.UploadService
+	signer  *utils.URLSigner
+	quotas  *services.UploadQuotaService
+	logger  *slog.Logger
+}
+
+func NewUploadController(service *services.UploadService, signer *utils.URLSigner, quotas *services.UploadQuotaService, logger *slog.Logger) *UploadController {
+	return &UploadController{service: service, signer: signer, quotas: quotas, logger: logger}
+}
+
+func (c *UploadController) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid Upload-Length header", http.StatusBadRequest)
+		return
+	}
+
+	metadata := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	filename := metadata["filename"]
+	if filename == "" {
+		http.Error(w, "filename is required in Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+
+	// The declared length counts towards the quota as soon as the upload
+	// is created
+	upload, err := c.service.Create(r.Context(), filename, length)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUploadTooLarge):
+			w.Header().Set("Tus-Max-Size", strconv.FormatInt(c.service.MaxSize(), 10))
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, services.ErrUploadQuotaExceeded) || strings.Contains(err.Error(), "upload quota"):
+			writeQuotaError(w, err, c.quotas)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/uploads/"+upload.ID)
+	w.Header().Set("Upload-Offset", "0")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(upload)
+}
+
+func (c *UploadController) GetUploadOffset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+
+	upload, err := c.service.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *UploadController) PatchUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid Upload-Offset header", http.StatusBadRequest)
+		return
+	}
+
+	upload, err := c.service.WriteChunk(mux.Vars(r)["id"], offset, r.Body)
+	if upload != nil {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	}
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *UploadController) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if err := c.service.Delete(mux.Vars(r)["id"]); err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *UploadController) FinalizeUpload(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProductID int `json:"productId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ProductID < 1 {
+		http.Error(w, "productId is required", http.StatusBadRequest)
+		return
+	}
+
+	product, err := c.service.Attach(r.Context(), mux.Vars(r)["id"], req.ProductID)
+	if err != nil {
+		c.logger.WarnContext(r.Context(), "finalizing upload failed", "upload_id", mux.Vars(r)["id"], "product_id", req.ProductID, "error", err)
+		writeUploadError(w, err)
+		return
+	}
+	signImage(c.signer, product)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// GetQuota returns the upload usage of the caller for today.
+func (c *UploadController) GetQuota(w http.ResponseWriter, r *http.Request) {
+	usage, err := c.quotas.Usage(r.Context())
+	if err != nil {
+		http.Error(w, "Error loading upload quota", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(usage)
+}
+
+func writeQuotaError(w http.ResponseWriter, err error, quotas *services.UploadQuotaService) {
+	switch {
+	case errors.Is(err, services.ErrImageTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, services.ErrUploadQuotaExceeded):
+		w.Header().Set("Retry-After", strconv.Itoa(int(quotas.RetryAfter().Seconds())+1))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// isBodyTooLarge reports whether reading the body hit the limit set by the
+// body limit middleware.
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func writeUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrUploadOffsetMismatch):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrUploadIncomplete):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseUploadMetadata decodes the tus Upload-Metadata header,
+// a comma separated list of "key base64(value)" pairs.
+func parseUploadMetadata(header string) map[string]string {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		metadata[key] = string(value)
+	}
+	return metadata
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Scopes an API key can be granted.
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeImages = "images"
+)
+
+func IsValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeWrite || scope == ScopeImages
+}
+
+// ScopeForPermission maps a route permission to the API key scope that
+// grants it. Permissions without a scope are never granted to API keys.
+func ScopeForPermission(permission string) string {
+	switch permission {
+	case PermissionProductsRead:
+		return ScopeRead
+	case PermissionProductsReadPrivate, PermissionProductsCreate, PermissionProductsUpdate, PermissionProductsDelete, PermissionProductsImport:
+		return ScopeWrite
+	case PermissionImagesUpload:
+		return ScopeImages
+	default:
+		return ""
+	}
+}
+
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// CreatedAPIKey is returned once on creation, the only time the plain
+// key is available.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyRepository interface {
+	Create(key *APIKey) error
+	List() ([]APIKey, error)
+	GetByHash(keyHash string) (*APIKey, error)
+	Revoke(id int) error
+	TouchLastUsed(id int) error
+}
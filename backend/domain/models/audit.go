@@ -0,0 +1,102 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditEntry records one change of a product: who made it, in which
+// request, and the product before and after.
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	ProductID int                    `json:"productId"`
+	Action    string                 `json:"action"`
+	Actor     string                 `json:"actor"`
+	RequestID string                 `json:"requestId"`
+	Before    json.RawMessage        `json:"before"`
+	After     json.RawMessage        `json:"after"`
+	Diff      map[string]FieldChange `json:"diff"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+type AuditFilter struct {
+	ProductID int
+	Actor     string
+	Action    string
+	From      *time.Time
+	To        *time.Time
+	Page      int
+	PageSize  int
+}
+
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	Total      int          `json:"total"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"pageSize"`
+	TotalPages int          `json:"totalPages"`
+}
+
+type AuditRepository interface {
+	List(ctx context.Context, filter AuditFilter) (*AuditPage, error)
+}
+
+// DiffProducts returns the JSON fields that differ between two versions of
+// a product. A nil version (before a create, after a delete) has no fields.
+func DiffProducts(before *Product, after *Product) (map[string]FieldChange, error) {
+	beforeFields, err := productFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := productFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(map[string]FieldChange)
+	for key, from := range beforeFields {
+		if to, ok := afterFields[key]; !ok || !reflect.DeepEqual(from, to) {
+			diff[key] = FieldChange{From: from, To: afterFields[key]}
+		}
+	}
+	for key, to := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			diff[key] = FieldChange{From: nil, To: to}
+		}
+	}
+
+	return diff, nil
+}
+
+func productFields(product *Product) (map[string]interface{}, error) {
+	if product == nil {
+		return nil, nil
+	}
+
+	snapshot := *product
+	snapshot.Image = ""
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores encoded values for the caching repositories. The in-process
+// implementation is utils.MemoryCache, a shared cache such as Redis can be
+// swapped in by implementing the same methods.
+type Cache interface {
+	// Get returns the value stored under key, ok is false on a miss or
+	// when the entry expired.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every key starting with prefix.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
@@ -0,0 +1,20 @@
+package models
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthCheck is the outcome of one readiness check. The response is
+// public, the reason a check failed is only logged.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// HealthReport is returned by the readiness endpoint, Status is "ok" only
+// when every check passed.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "context"
+
+// ImageRepository keeps track of stored image files and how many
+// products reference each of them.
+type ImageRepository interface {
+	ReplaceImageURL(oldURL string, newURL string) (int64, error)
+	RebuildRefCounts() error
+	PruneUnreferenced() (int64, error)
+	// IsReferenced reports whether a product or a revision uses the image.
+	IsReferenced(ctx context.Context, imageURL string) (bool, error)
+}
+
+type MigratedImage struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Products int64  `json:"products"`
+	Deduped  bool   `json:"deduped"`
+}
+
+// ImageMigrationReport describes the move of legacy "product-<nanos>" files
+// to content-addressed names.
+type ImageMigrationReport struct {
+	DryRun   bool            `json:"dryRun"`
+	Migrated []MigratedImage `json:"migrated"`
+	Failed   []string        `json:"failed"`
+}
+
+// ImagePreset is one of the renditions clients are allowed to request.
+// Fit is "cover" (fill the box and crop), "contain" (fit inside the box)
+// or "fill" (stretch to the box).
+type ImagePreset struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Fit    string `json:"fit"`
+}
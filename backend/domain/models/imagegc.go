@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type OrphanedImage struct {
+	Filename   string    `json:"filename"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+	Deleted    bool      `json:"deleted"`
+}
+
+// GCReport summarises one reconciliation run between the uploads
+// directory and the image_url references in the products table.
+type GCReport struct {
+	StartedAt   time.Time       `json:"startedAt"`
+	DryRun      bool            `json:"dryRun"`
+	GracePeriod string          `json:"gracePeriod"`
+	Scanned     int             `json:"scanned"`
+	Referenced  int             `json:"referenced"`
+	Orphans     []OrphanedImage `json:"orphans"`
+	Deleted     int             `json:"deleted"`
+	FreedBytes  int64           `json:"freedBytes"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	// Dead jobs used up their attempts and wait for a manual retry
+	JobStatusDead = "dead"
+)
+
+// Job types run by the workers
+const (
+	JobTypeImageRenditions = "image.renditions"
+	JobTypeImageGC         = "image.gc"
+	// A webhook event is dispatched into one delivery per subscription,
+	// each sent by its own job so that retries are per receiver
+	JobTypeWebhookDispatch = "webhook.dispatch"
+	JobTypeWebhookDeliver  = "webhook.deliver"
+)
+
+type Job struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	UniqueKey   string          `json:"uniqueKey,omitempty"`
+	RunAt       time.Time       `json:"runAt"`
+	LockedBy    string          `json:"lockedBy,omitempty"`
+	LockedAt    *time.Time      `json:"lockedAt,omitempty"`
+	LastError   string          `json:"lastError,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// ImageJobPayload is the payload of jobs working on one stored image.
+type ImageJobPayload struct {
+	ImageURL string `json:"imageUrl"`
+}
+
+type GCJobPayload struct {
+	DryRun bool `json:"dryRun"`
+}
+
+type JobFilter struct {
+	Status   string
+	Type     string
+	Page     int
+	PageSize int
+}
+
+type JobPage struct {
+	Jobs       []Job `json:"jobs"`
+	Total      int   `json:"total"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	TotalPages int   `json:"totalPages"`
+}
+
+type JobRepository interface {
+	// Enqueue inserts the job, inside the unit of work in ctx if there is
+	// one. It returns false when a job with the same unique key is already
+	// pending or running.
+	Enqueue(ctx context.Context, job *Job) (bool, error)
+	// Claim locks the next due job of one of the given types for worker,
+	// nil when there is none.
+	Claim(ctx context.Context, types []string, worker string) (*Job, error)
+	// Complete and Fail only apply while worker still holds the job, a job
+	// requeued as stale and claimed by another worker is left alone.
+	Complete(ctx context.Context, id int64, worker string) error
+	// Fail records the error and schedules the job again at retryAt, a nil
+	// retryAt moves it to the dead jobs.
+	Fail(ctx context.Context, id int64, worker string, lastError string, retryAt *time.Time) error
+	// RequeueStale releases running jobs locked before lockedBefore, their
+	// worker died without finishing them.
+	RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error)
+	List(ctx context.Context, filter JobFilter) (*JobPage, error)
+	Get(ctx context.Context, id int64) (*Job, error)
+	// Retry makes a dead job pending again with fresh attempts.
+	Retry(ctx context.Context, id int64) (*Job, error)
+}
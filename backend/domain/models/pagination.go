@@ -15,6 +15,8 @@ type FilterParams struct {
 	Type      string  `json:"type"`
 	SortBy    string  `json:"sortBy"`
 	SortOrder string  `json:"sortOrder"`
+	// IncludePrivate is set for callers allowed to see private products
+	IncludePrivate bool `json:"includePrivate"`
 }
 
 type PaginatedResponse struct {
@@ -0,0 +1,34 @@
+package models
+
+import "context"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject    string `json:"subject"`
+	Role       string `json:"role,omitempty"`
+	AuthMethod string `json:"authMethod"`
+	// Scopes limit what an API key principal may do
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalContextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller attached by the authentication
+// middleware, or nil for anonymous requests.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
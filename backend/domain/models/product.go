@@ -1,7 +1,10 @@
 // models/product.go
 package models
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Product struct {
 	ID          int       `json:"id" db:"id"`
@@ -11,15 +14,20 @@ type Product struct {
 	Description string    `json:"description" db:"description"`
 	ImageURL    string    `json:"image_url" db:"image_url"`
 	Image       string    `json:"image,omitempty"` // for base64 data
+	Private     bool      `json:"private" db:"private"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
 type ProductRepository interface {
-	GetAll(page int, pageSize int) ([]Product, error)
-	GetProducts(params FilterParams) (*PaginatedResponse, error)
-	GetByID(id int) (*Product, error)
-	Create(product *Product) error
-	Update(product *Product) error
-	Delete(id int) error
-	Search(name string) ([]Product, error)
+	// GetAll, GetProducts and Search leave out private products unless
+	// includePrivate (or FilterParams.IncludePrivate) is set
+	GetAll(ctx context.Context, page int, pageSize int, includePrivate bool) ([]Product, error)
+	GetProducts(ctx context.Context, params FilterParams) (*PaginatedResponse, error)
+	GetByID(ctx context.Context, id int) (*Product, error)
+	Create(ctx context.Context, product *Product) error
+	Update(ctx context.Context, product *Product) error
+	Delete(ctx context.Context, id int) error
+	Search(ctx context.Context, name string, includePrivate bool) ([]Product, error)
+	GetImageURLs(ctx context.Context) ([]string, error)
+	IsPrivateImage(ctx context.Context, imageURL string) (bool, error)
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// ProductRevision is a snapshot of a product as it was before an update.
+// Revisions are numbered per product starting at 1.
+type ProductRevision struct {
+	ID        int64     `json:"id"`
+	ProductID int       `json:"productId"`
+	Revision  int       `json:"revision"`
+	Product   Product   `json:"product"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type RevisionDiff struct {
+	ProductID int                    `json:"productId"`
+	From      int                    `json:"from"`
+	To        int                    `json:"to"`
+	Changes   map[string]FieldChange `json:"changes"`
+}
+
+type RevisionRepository interface {
+	List(ctx context.Context, productID int) ([]ProductRevision, error)
+	Get(ctx context.Context, productID int, revision int) (*ProductRevision, error)
+}
@@ -0,0 +1,34 @@
+package models
+
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Permissions checked by the routes. Which role holds which permission is
+// stored in the role_permissions table.
+const (
+	PermissionProductsRead = "products:read"
+	// Private (draft) products are hidden from callers without this one
+	PermissionProductsReadPrivate = "products:read_private"
+	PermissionProductsCreate      = "products:create"
+	PermissionProductsUpdate      = "products:update"
+	PermissionProductsDelete      = "products:delete"
+	PermissionProductsImport      = "products:import"
+	PermissionImagesUpload        = "images:upload"
+	PermissionUsersManage         = "users:manage"
+	PermissionAPIKeysManage       = "apikeys:manage"
+	PermissionAuditRead           = "audit:read"
+	PermissionJobsManage          = "jobs:manage"
+	PermissionWebhooksManage      = "webhooks:manage"
+)
+
+func IsValidRole(role string) bool {
+	return role == RoleViewer || role == RoleEditor || role == RoleAdmin
+}
+
+type PolicyRepository interface {
+	// GetRolePermissions returns the permissions granted to each role
+	GetRolePermissions() (map[string][]string, error)
+}
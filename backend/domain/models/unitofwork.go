@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"sync"
+)
+
+// UnitOfWork runs several repository calls in one database transaction.
+// The transaction travels in the context passed to fn, repositories called
+// with that context join it instead of starting their own. It commits when
+// fn returns nil and rolls back otherwise.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// TxHooks collects the side effects outside the database of a unit of
+// work: what to undo if it rolls back and what to do once it committed.
+type TxHooks struct {
+	mu          sync.Mutex
+	onRollback  []func()
+	afterCommit []func()
+}
+
+type txHooksContextKey struct{}
+
+func WithTxHooks(ctx context.Context) (context.Context, *TxHooks) {
+	hooks := &TxHooks{}
+	return context.WithValue(ctx, txHooksContextKey{}, hooks), hooks
+}
+
+// InUnitOfWork reports whether ctx carries a unit of work.
+func InUnitOfWork(ctx context.Context) bool {
+	_, ok := ctx.Value(txHooksContextKey{}).(*TxHooks)
+	return ok
+}
+
+// OnRollback registers a compensation, e.g. removing a file written during
+// the unit of work in ctx. Outside a unit of work there is nothing to roll
+// back and fn is dropped.
+func OnRollback(ctx context.Context, fn func()) {
+	if hooks, ok := ctx.Value(txHooksContextKey{}).(*TxHooks); ok {
+		hooks.mu.Lock()
+		hooks.onRollback = append(hooks.onRollback, fn)
+		hooks.mu.Unlock()
+	}
+}
+
+// AfterCommit defers fn until the unit of work in ctx committed, outside a
+// unit of work the change is already committed and fn runs right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	if hooks, ok := ctx.Value(txHooksContextKey{}).(*TxHooks); ok {
+		hooks.mu.Lock()
+		hooks.afterCommit = append(hooks.afterCommit, fn)
+		hooks.mu.Unlock()
+		return
+	}
+	fn()
+}
+
+// RolledBack runs the compensations, most recent first.
+func (h *TxHooks) RolledBack() {
+	h.mu.Lock()
+	fns := h.onRollback
+	h.onRollback, h.afterCommit = nil, nil
+	h.mu.Unlock()
+
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
+
+// Committed runs the after commit hooks in registration order.
+func (h *TxHooks) Committed() {
+	h.mu.Lock()
+	fns := h.afterCommit
+	h.onRollback, h.afterCommit = nil, nil
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
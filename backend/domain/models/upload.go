@@ -0,0 +1,50 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Upload is a resumable upload in progress. The received bytes live in a
+// partial file next to its metadata until the upload is finalized.
+type Upload struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Quota is the reservation of the declared length against the daily
+	// upload quota, given back when the upload is aborted or expires
+	Quota *QuotaReservation `json:"quota,omitempty"`
+}
+
+func (u *Upload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+// UploadUsage is what a principal uploaded on one UTC day.
+type UploadUsage struct {
+	Subject string    `json:"subject"`
+	Day     time.Time `json:"day"`
+	Bytes   int64     `json:"bytes"`
+	Limit   int64     `json:"limit"`
+}
+
+// QuotaReservation is an amount counted towards the upload quota of
+// subject on day.
+type QuotaReservation struct {
+	Subject string    `json:"subject"`
+	Day     time.Time `json:"day"`
+	Bytes   int64     `json:"bytes"`
+}
+
+type UploadUsageRepository interface {
+	// Reserve adds bytes to the usage of subject on day unless the total
+	// would exceed limit. It returns whether the bytes were added and the
+	// usage after the call.
+	Reserve(ctx context.Context, subject string, day time.Time, bytes int64, limit int64) (bool, int64, error)
+	Get(ctx context.Context, subject string, day time.Time) (int64, error)
+	// Release takes bytes back off the usage of subject on day
+	Release(ctx context.Context, subject string, day time.Time, bytes int64) error
+}
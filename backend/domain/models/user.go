@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type User struct {
+	ID           int       `json:"id" db:"id"`
+	Email        string    `json:"email" db:"email"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	Role         string    `json:"role" db:"role"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	FamilyID  string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// TokenPair is returned by login and refresh.
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+type UserRepository interface {
+	Create(user *User) error
+	GetByID(id int) (*User, error)
+	GetByEmail(email string) (*User, error)
+	UpdateRole(id int, role string) error
+
+	CreateRefreshToken(token *RefreshToken) error
+	GetRefreshToken(tokenHash string) (*RefreshToken, error)
+	RevokeRefreshToken(id int) error
+	RevokeRefreshTokenFamily(familyID string) error
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Product lifecycle events webhooks can subscribe to.
+const (
+	EventProductCreated = "product.created"
+	EventProductUpdated = "product.updated"
+	EventProductDeleted = "product.deleted"
+	// EventPing is only sent on request, to test a subscription
+	EventPing = "ping"
+)
+
+func IsValidEventType(eventType string) bool {
+	return eventType == EventProductCreated || eventType == EventProductUpdated || eventType == EventProductDeleted
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	// Failed deliveries are retried by their job until it runs out of
+	// attempts, after that they can be replayed
+	WebhookDeliveryFailed = "failed"
+)
+
+type WebhookSubscription struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"eventTypes"`
+	Active     bool      `json:"active"`
+	CreatedBy  string    `json:"createdBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// CreatedWebhookSubscription is returned once on creation, the only time
+// the signing secret is shown.
+type CreatedWebhookSubscription struct {
+	WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// WebhookEvent is the body POSTed to subscribers. The id stays the same
+// across retries and replays, receivers use it to drop duplicates.
+type WebhookEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// WebhookDelivery is one event sent to one subscription, with the outcome
+// of its last attempt.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int             `json:"subscriptionId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"responseStatus,omitempty"`
+	ResponseBody   string          `json:"responseBody,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	DurationMs     *int            `json:"durationMs,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+type WebhookDeliveryJobPayload struct {
+	DeliveryID int64 `json:"deliveryId"`
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID int
+	Status         string
+	Page           int
+	PageSize       int
+}
+
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int               `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"pageSize"`
+	TotalPages int               `json:"totalPages"`
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int) (*WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int) error
+	// SubscriptionsFor returns the active subscriptions to eventType
+	SubscriptionsFor(ctx context.Context, eventType string) ([]WebhookSubscription, error)
+
+	// CreateDelivery inserts the delivery, inside the unit of work in ctx if
+	// there is one. It returns false when the event was already recorded for
+	// the subscription.
+	CreateDelivery(ctx context.Context, delivery *WebhookDelivery) (bool, error)
+	GetDelivery(ctx context.Context, id int64) (*WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) (*WebhookDeliveryPage, error)
+	// RecordAttempt stores the outcome of a delivery attempt
+	RecordAttempt(ctx context.Context, delivery *WebhookDelivery) error
+	// ResetDelivery makes a failed delivery pending again, inside the unit
+	// of work in ctx if there is one.
+	ResetDelivery(ctx context.Context, id int64) error
+}
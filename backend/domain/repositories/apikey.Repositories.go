@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+)
+
+type PostgresAPIKeyRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewAPIKeyRepository(db *sql.DB, logger *slog.Logger) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db, logger: logger}
+}
+
+// Create implements models.APIKeyRepository.
+func (r *PostgresAPIKeyRepository) Create(key *models.APIKey) error {
+	defer metrics.ObserveQuery("api_keys", "Create")()
+
+	query := `
+	INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+
+	err := r.db.QueryRow(
+		query,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+		key.CreatedBy,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		r.logger.Error("creating API key failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// List implements models.APIKeyRepository.
+func (r *PostgresAPIKeyRepository) List() ([]models.APIKey, error) {
+	defer metrics.ObserveQuery("api_keys", "List")()
+
+	query := `SELECT id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_by, created_at 
+	FROM api_keys 
+	ORDER BY id`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		r.logger.Error("listing API keys failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			r.logger.Error("scanning API key row failed", "error", err)
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetByHash implements models.APIKeyRepository.
+func (r *PostgresAPIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	defer metrics.ObserveQuery("api_keys", "GetByHash")()
+
+	query := `SELECT id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_by, created_at 
+	FROM api_keys 
+	WHERE key_hash = $1`
+
+	key, err := scanAPIKey(r.db.QueryRow(query, keyHash))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("API key not found")
+	}
+	if err != nil {
+		r.logger.Error("getting API key failed", "error", err)
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Revoke implements models.APIKeyRepository.
+func (r *PostgresAPIKeyRepository) Revoke(id int) error {
+	defer metrics.ObserveQuery("api_keys", "Revoke")()
+
+	result, err := r.db.Exec(`UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		r.logger.Error("revoking API key failed", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("API key with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// TouchLastUsed implements models.APIKeyRepository.
+// The timestamp is only written once a minute to keep busy keys from
+// turning every request into a write.
+func (r *PostgresAPIKeyRepository) TouchLastUsed(id int) error {
+	defer metrics.ObserveQuery("api_keys", "TouchLastUsed")()
+
+	query := `UPDATE api_keys SET last_used_at = now() 
+	WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`
+
+	if _, err := r.db.Exec(query, id); err != nil {
+		r.logger.Error("updating API key last use failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var createdBy sql.NullString
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&createdBy,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	key.CreatedBy = createdBy.String
+	return &key, nil
+}
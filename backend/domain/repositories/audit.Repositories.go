@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"PRODUCT_LIST/tracing"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+type PostgresAuditRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewAuditRepository(db *sql.DB, logger *slog.Logger) *PostgresAuditRepository {
+	return &PostgresAuditRepository{db: db, logger: logger}
+}
+
+// recordAudit writes the audit entry of a product change inside the
+// transaction making the change. The actor and request id come from the
+// request context.
+func recordAudit(ctx context.Context, tx *sql.Tx, logger *slog.Logger, productID int, action string, before *models.Product, after *models.Product) error {
+	actor := "anonymous"
+	if principal := models.PrincipalFromContext(ctx); principal != nil {
+		actor = principal.Subject
+	}
+
+	beforeJSON, err := productSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := productSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	changes, err := models.DiffProducts(before, after)
+	if err != nil {
+		return err
+	}
+	diff, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO audit_log (product_id, action, actor, request_id, before, after, diff)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	queryCtx, span := tracing.StartQuery(ctx, "INSERT audit_log", query)
+	_, err = tx.ExecContext(
+		queryCtx,
+		query,
+		productID,
+		action,
+		actor,
+		models.RequestIDFromContext(ctx),
+		beforeJSON,
+		afterJSON,
+		diff,
+	)
+	tracing.End(span, err)
+	if err != nil {
+		logger.ErrorContext(ctx, "recording audit entry failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// productSnapshot returns the JSON stored for a product version, nil for a
+// missing version. The base64 upload payload is never stored.
+func productSnapshot(product *models.Product) ([]byte, error) {
+	if product == nil {
+		return nil, nil
+	}
+
+	snapshot := *product
+	snapshot.Image = ""
+	return json.Marshal(snapshot)
+}
+
+// List implements models.AuditRepository.
+func (r *PostgresAuditRepository) List(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error) {
+	defer metrics.ObserveQuery("audit_log", "List")()
+
+	baseQuery := `
+        SELECT COUNT(*) OVER(), id, product_id, action, actor, request_id, before, after, diff, created_at
+        FROM audit_log
+        WHERE 1=1`
+
+	queryParams := make([]interface{}, 0)
+	paramCount := 1
+
+	if filter.ProductID > 0 {
+		baseQuery += fmt.Sprintf(" AND product_id = $%d", paramCount)
+		queryParams = append(queryParams, filter.ProductID)
+		paramCount++
+	}
+
+	if filter.Actor != "" {
+		baseQuery += fmt.Sprintf(" AND actor = $%d", paramCount)
+		queryParams = append(queryParams, filter.Actor)
+		paramCount++
+	}
+
+	if filter.Action != "" {
+		baseQuery += fmt.Sprintf(" AND action = $%d", paramCount)
+		queryParams = append(queryParams, filter.Action)
+		paramCount++
+	}
+
+	if filter.From != nil {
+		baseQuery += fmt.Sprintf(" AND created_at >= $%d", paramCount)
+		queryParams = append(queryParams, *filter.From)
+		paramCount++
+	}
+
+	if filter.To != nil {
+		baseQuery += fmt.Sprintf(" AND created_at <= $%d", paramCount)
+		queryParams = append(queryParams, *filter.To)
+		paramCount++
+	}
+
+	// Newest first, pagination like GetProducts
+	offset := (filter.Page - 1) * filter.PageSize
+	baseQuery += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+	queryParams = append(queryParams, filter.PageSize, offset)
+
+	rows, err := queryRead(ctx, r.db, baseQuery, queryParams...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "listing audit entries failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	var total int
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		var diff []byte
+		err := rows.Scan(
+			&total,
+			&entry.ID,
+			&entry.ProductID,
+			&entry.Action,
+			&entry.Actor,
+			&entry.RequestID,
+			&before,
+			&after,
+			&diff,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "scanning audit row failed", "error", err)
+			return nil, err
+		}
+
+		entry.Before = nullableJSON(before)
+		entry.After = nullableJSON(after)
+		if err := json.Unmarshal(diff, &entry.Diff); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.AuditPage{
+		Entries:    entries,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalPages: (total + filter.PageSize - 1) / filter.PageSize,
+	}, nil
+}
+
+func nullableJSON(data []byte) json.RawMessage {
+	if data == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(data)
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/metrics"
+	"PRODUCT_LIST/tracing"
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+type PostgresImageRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewImageRepository(db *sql.DB, logger *slog.Logger) *PostgresImageRepository {
+	return &PostgresImageRepository{db: db, logger: logger}
+}
+
+// adjustImageRef moves the reference count of a stored image by delta
+// inside the transaction that changes the product row.
+func adjustImageRef(ctx context.Context, tx *sql.Tx, logger *slog.Logger, imageURL string, delta int) error {
+	if imageURL == "" {
+		return nil
+	}
+
+	query := `
+	INSERT INTO images (image_url, ref_count)
+	VALUES ($1, GREATEST($2, 0))
+	ON CONFLICT (image_url)
+	DO UPDATE SET ref_count = GREATEST(images.ref_count + $2, 0)`
+
+	ctx, span := tracing.StartQuery(ctx, "UPSERT images", query)
+	_, err := tx.ExecContext(ctx, query, imageURL, delta)
+	tracing.End(span, err)
+	if err != nil {
+		logger.ErrorContext(ctx, "updating image reference count failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ReplaceImageURL implements models.ImageRepository.
+func (r *PostgresImageRepository) ReplaceImageURL(oldURL string, newURL string) (int64, error) {
+	defer metrics.ObserveQuery("images", "ReplaceImageURL")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE products SET image_url = $1 WHERE image_url = $2`, newURL, oldURL)
+	if err != nil {
+		r.logger.Error("replacing image URL failed", "error", err)
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE product_revisions SET image_url = $1 WHERE image_url = $2`, newURL, oldURL); err != nil {
+		r.logger.Error("replacing revision image URL failed", "error", err)
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return affected, tx.Commit()
+}
+
+// RebuildRefCounts implements models.ImageRepository.
+func (r *PostgresImageRepository) RebuildRefCounts() error {
+	defer metrics.ObserveQuery("images", "RebuildRefCounts")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE images SET ref_count = 0`); err != nil {
+		r.logger.Error("resetting image reference counts failed", "error", err)
+		return err
+	}
+
+	query := `
+	INSERT INTO images (image_url, ref_count)
+	SELECT image_url, COUNT(*) FROM products
+	WHERE image_url IS NOT NULL AND image_url <> ''
+	GROUP BY image_url
+	ON CONFLICT (image_url)
+	DO UPDATE SET ref_count = excluded.ref_count`
+
+	if _, err := tx.Exec(query); err != nil {
+		r.logger.Error("rebuilding image reference counts failed", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PruneUnreferenced implements models.ImageRepository.
+func (r *PostgresImageRepository) PruneUnreferenced() (int64, error) {
+	defer metrics.ObserveQuery("images", "PruneUnreferenced")()
+
+	result, err := r.db.Exec(`DELETE FROM images WHERE ref_count <= 0`)
+	if err != nil {
+		r.logger.Error("pruning images failed", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// IsReferenced implements models.ImageRepository.
+func (r *PostgresImageRepository) IsReferenced(ctx context.Context, imageURL string) (bool, error) {
+	defer metrics.ObserveQuery("images", "IsReferenced")()
+
+	query := `SELECT EXISTS (
+		SELECT 1 FROM images WHERE image_url = $1 AND ref_count > 0
+	) OR EXISTS (
+		SELECT 1 FROM product_revisions WHERE image_url = $1
+	)`
+
+	var referenced bool
+	if err := r.db.QueryRowContext(ctx, query, imageURL).Scan(&referenced); err != nil {
+		r.logger.ErrorContext(ctx, "checking image references failed", "error", err)
+		return false, err
+	}
+	return referenced, nil
+}
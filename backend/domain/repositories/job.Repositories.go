@@ -0,0 +1,295 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PostgresJobRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewJobRepository(db *sql.DB, logger *slog.Logger) *PostgresJobRepository {
+	return &PostgresJobRepository{db: db, logger: logger}
+}
+
+const jobColumns = `id, type, payload, status, attempts, max_attempts, unique_key, run_at,
+	locked_by, locked_at, last_error, created_at, finished_at`
+
+func scanJob(row rowScanner) (*models.Job, error) {
+	var job models.Job
+	var payload []byte
+	var uniqueKey, lockedBy, lastError sql.NullString
+	var lockedAt, finishedAt sql.NullTime
+
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&uniqueKey,
+		&job.RunAt,
+		&lockedBy,
+		&lockedAt,
+		&lastError,
+		&job.CreatedAt,
+		&finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Payload = payload
+	job.UniqueKey = uniqueKey.String
+	job.LockedBy = lockedBy.String
+	job.LastError = lastError.String
+	if lockedAt.Valid {
+		job.LockedAt = &lockedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// Enqueue implements models.JobRepository. Inside a unit of work the job
+// only becomes visible to workers once the change that queued it commits.
+func (r *PostgresJobRepository) Enqueue(ctx context.Context, job *models.Job) (bool, error) {
+	defer metrics.ObserveQuery("jobs", "Enqueue")()
+
+	var conn queryer = r.db
+	if tx, ok := txFromContext(ctx); ok {
+		conn = tx
+	}
+
+	query := `
+	INSERT INTO jobs (type, payload, max_attempts, unique_key, run_at)
+	VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+	ON CONFLICT (unique_key) WHERE status IN ('pending', 'running') DO NOTHING
+	RETURNING id, status, created_at`
+
+	err := conn.QueryRowContext(ctx, query, job.Type, []byte(job.Payload), job.MaxAttempts, job.UniqueKey, job.RunAt).
+		Scan(&job.ID, &job.Status, &job.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "enqueueing job failed", "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// Claim implements models.JobRepository.
+func (r *PostgresJobRepository) Claim(ctx context.Context, types []string, worker string) (*models.Job, error) {
+	defer metrics.ObserveQuery("jobs", "Claim")()
+
+	// SKIP LOCKED lets concurrent workers, on this or other instances, each
+	// take a different job without waiting on each other
+	query := `
+	UPDATE jobs
+	SET status = 'running', attempts = attempts + 1, locked_by = $2, locked_at = now()
+	WHERE id = (
+		SELECT id FROM jobs
+		WHERE status = 'pending' AND run_at <= now() AND type = ANY($1)
+		ORDER BY run_at, id
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	)
+	RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, pq.Array(types), worker))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "claiming job failed", "error", err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// Complete implements models.JobRepository.
+func (r *PostgresJobRepository) Complete(ctx context.Context, id int64, worker string) error {
+	defer metrics.ObserveQuery("jobs", "Complete")()
+
+	result, err := r.db.ExecContext(ctx, `
+	UPDATE jobs
+	SET status = 'succeeded', locked_by = NULL, locked_at = NULL, finished_at = now()
+	WHERE id = $1 AND status = 'running' AND locked_by = $2`, id, worker)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "completing job failed", "job_id", id, "error", err)
+		return err
+	}
+	return lockLost(result, id, worker)
+}
+
+// lockLost reports a job update that matched nothing because the job is no
+// longer running under worker.
+func lockLost(result sql.Result, id int64, worker string) error {
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("job %d is no longer locked by %s", id, worker)
+	}
+	return nil
+}
+
+// Fail implements models.JobRepository.
+func (r *PostgresJobRepository) Fail(ctx context.Context, id int64, worker string, lastError string, retryAt *time.Time) error {
+	defer metrics.ObserveQuery("jobs", "Fail")()
+
+	var result sql.Result
+	var err error
+	if retryAt != nil {
+		result, err = r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'pending', run_at = $3, last_error = $4, locked_by = NULL, locked_at = NULL
+		WHERE id = $1 AND status = 'running' AND locked_by = $2`, id, worker, *retryAt, lastError)
+	} else {
+		result, err = r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'dead', last_error = $3, locked_by = NULL, locked_at = NULL, finished_at = now()
+		WHERE id = $1 AND status = 'running' AND locked_by = $2`, id, worker, lastError)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "recording job failure failed", "job_id", id, "error", err)
+		return err
+	}
+	return lockLost(result, id, worker)
+}
+
+// RequeueStale implements models.JobRepository.
+func (r *PostgresJobRepository) RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	defer metrics.ObserveQuery("jobs", "RequeueStale")()
+
+	// The interrupted run counts as an attempt, a job that keeps killing
+	// its worker ends up dead instead of looping forever
+	result, err := r.db.ExecContext(ctx, `
+	UPDATE jobs
+	SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+		last_error = 'worker stopped before finishing the job',
+		finished_at = CASE WHEN attempts >= max_attempts THEN now() END,
+		locked_by = NULL, locked_at = NULL
+	WHERE status = 'running' AND locked_at < $1`, lockedBefore)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "requeueing stale jobs failed", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// List implements models.JobRepository.
+func (r *PostgresJobRepository) List(ctx context.Context, filter models.JobFilter) (*models.JobPage, error) {
+	defer metrics.ObserveQuery("jobs", "List")()
+
+	baseQuery := `SELECT COUNT(*) OVER(), ` + jobColumns + `
+	FROM jobs
+	WHERE 1=1`
+
+	queryParams := make([]interface{}, 0)
+	paramCount := 1
+
+	if filter.Status != "" {
+		baseQuery += fmt.Sprintf(" AND status = $%d", paramCount)
+		queryParams = append(queryParams, filter.Status)
+		paramCount++
+	}
+
+	if filter.Type != "" {
+		baseQuery += fmt.Sprintf(" AND type = $%d", paramCount)
+		queryParams = append(queryParams, filter.Type)
+		paramCount++
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	baseQuery += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+	queryParams = append(queryParams, filter.PageSize, offset)
+
+	rows, err := queryRead(ctx, r.db, baseQuery, queryParams...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "listing jobs failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	var total int
+
+	for rows.Next() {
+		var job *models.Job
+		job, err = scanJob(totalScanner{rows, &total})
+		if err != nil {
+			r.logger.ErrorContext(ctx, "scanning job row failed", "error", err)
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.JobPage{
+		Jobs:       jobs,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalPages: (total + filter.PageSize - 1) / filter.PageSize,
+	}, nil
+}
+
+// totalScanner reads the leading COUNT(*) OVER() column of a page query
+// before handing the rest of the row to a scan function.
+type totalScanner struct {
+	rows  *sql.Rows
+	total *int
+}
+
+func (s totalScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append([]interface{}{s.total}, dest...)...)
+}
+
+// Get implements models.JobRepository.
+func (r *PostgresJobRepository) Get(ctx context.Context, id int64) (*models.Job, error) {
+	defer metrics.ObserveQuery("jobs", "Get")()
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job %d not found", id)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "getting job failed", "error", err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// Retry implements models.JobRepository.
+func (r *PostgresJobRepository) Retry(ctx context.Context, id int64) (*models.Job, error) {
+	defer metrics.ObserveQuery("jobs", "Retry")()
+
+	query := `
+	UPDATE jobs
+	SET status = 'pending', attempts = 0, run_at = now(), finished_at = NULL
+	WHERE id = $1 AND status = 'dead'
+	RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("dead job %d not found", id)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "retrying job failed", "error", err)
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/metrics"
+	"database/sql"
+	"log/slog"
+)
+
+type PostgresPolicyRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewPolicyRepository(db *sql.DB, logger *slog.Logger) *PostgresPolicyRepository {
+	return &PostgresPolicyRepository{db: db, logger: logger}
+}
+
+// GetRolePermissions implements models.PolicyRepository.
+func (r *PostgresPolicyRepository) GetRolePermissions() (map[string][]string, error) {
+	defer metrics.ObserveQuery("role_permissions", "GetRolePermissions")()
+
+	rows, err := r.db.Query(`SELECT role, permission FROM role_permissions`)
+	if err != nil {
+		r.logger.Error("getting role permissions failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	policy := make(map[string][]string)
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			r.logger.Error("scanning role permission failed", "error", err)
+			return nil, err
+		}
+		policy[role] = append(policy[role], permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
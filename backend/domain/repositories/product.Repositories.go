@@ -2,99 +2,146 @@ package repositories
 
 import (
 	"PRODUCT_LIST/domain/models"
-	"PRODUCT_LIST/utils"
+	"PRODUCT_LIST/metrics"
+	"PRODUCT_LIST/tracing"
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"regexp"
 )
 
+// PostgresProductRepository writes to the primary and serves catalog reads
+// from the replicas when there are any.
 type PostgresProductRepository struct {
-	db *sql.DB
+	db       *sql.DB
+	replicas *ReplicaSet
+	logger   *slog.Logger
 }
 
 // Create implements models.ProductRepository.
-func (r *PostgresProductRepository) Create(product *models.Product) error {
-	// Handle base64 image if present
-	if product.Image != "" {
-		imageURL, err := utils.SaveBase64Image(product.Image)
-		if err != nil {
-			log.Printf("Error saving image: %v", err)
-			return err
-		}
-		product.ImageURL = imageURL
-	}
+func (r *PostgresProductRepository) Create(ctx context.Context, product *models.Product) error {
+	defer metrics.ObserveQuery("products", "Create")()
 
-	// Add logging to see what's being received
-	log.Printf("Attempting to create product with image URL: %s", product.ImageURL)
+	r.logger.DebugContext(ctx, "creating product", "image_url", product.ImageURL)
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "starting transaction failed", "error", err)
+		return err
+	}
+	defer tx.Rollback()
 
 	query := `
-	INSERT INTO products (name, type, price, description, image_url)
-	VALUES ($1, $2, $3, $4, $5)
-	RETURNING id`
+	INSERT INTO products (name, type, price, description, image_url, private)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
 
-	err := r.db.QueryRow(
+	queryCtx, span := tracing.StartQuery(ctx, "INSERT products", query)
+	err = tx.QueryRowContext(
+		queryCtx,
 		query,
 		product.Name,
 		product.Type,
 		product.Price,
 		product.Description,
 		product.ImageURL,
-	).Scan(&product.ID)
+		product.Private,
+	).Scan(&product.ID, &product.CreatedAt)
+	tracing.End(span, err)
 
 	if err != nil {
-		log.Printf("Error creating product: %v", err)
+		r.logger.ErrorContext(ctx, "creating product failed", "error", err)
 		return err
 	}
 
-	// Add logging to verify the insert
-	log.Printf("Successfully created product with ID: %d", product.ID)
+	if err := adjustImageRef(ctx, tx.Tx, r.logger, product.ImageURL, 1); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx.Tx, r.logger, product.ID, models.AuditActionCreate, nil, product); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.ErrorContext(ctx, "committing product failed", "error", err)
+		return err
+	}
+	models.AfterCommit(ctx, func() { r.replicas.MarkWrite(ctx) })
+
+	r.logger.InfoContext(ctx, "product created", "product_id", product.ID)
 	return nil
 }
 
 // Delete implements models.ProductRepository.
-func (r *PostgresProductRepository) Delete(id int) error {
-	query := `DELETE FROM products WHERE id = $1`
+func (r *PostgresProductRepository) Delete(ctx context.Context, id int) error {
+	defer metrics.ObserveQuery("products", "Delete")()
 
-	result, err := r.db.Exec(query, id)
+	tx, err := beginTx(ctx, r.db)
 	if err != nil {
-		log.Printf("Error deleting productL %v", err)
+		r.logger.ErrorContext(ctx, "starting transaction failed", "error", err)
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	query := `DELETE FROM products WHERE id = $1 
+	RETURNING id, name, type, price, description, image_url, private, created_at`
+
+	queryCtx, span := tracing.StartQuery(ctx, "DELETE products", query)
+	deleted, err := scanProductRow(tx.QueryRowContext(queryCtx, query, id))
+	tracing.End(span, queryError(err))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("product with ID %d not found", id)
+	}
 	if err != nil {
+		r.logger.ErrorContext(ctx, "deleting product failed", "product_id", id, "error", err)
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("product with ID %d not found", id)
+	if err := adjustImageRef(ctx, tx.Tx, r.logger, deleted.ImageURL, -1); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx.Tx, r.logger, id, models.AuditActionDelete, deleted, nil); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	models.AfterCommit(ctx, func() { r.replicas.MarkWrite(ctx) })
 	return nil
 }
 
 // GetByID implements models.ProductRepository.
-func (r *PostgresProductRepository) GetByID(id int) (*models.Product, error) {
-	query := `SELECT id, name, type, price, description, image_url 
+func (r *PostgresProductRepository) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	defer metrics.ObserveQuery("products", "GetByID")()
+
+	query := `SELECT id, name, type, price, description, image_url, private 
 	FROM products 
 	WHERE id = $1`
 
+	queryCtx, span := tracing.StartQuery(ctx, "SELECT products", query)
+	db := r.reader(ctx)
 	product := &models.Product{}
-	err := r.db.QueryRow(query, id).Scan(
-		&product.ID,
-		&product.Name,
-		&product.Type,
-		&product.Price,
-		&product.Description,
-		&product.ImageURL,
-	)
+	err := retryRead(queryCtx, func() error {
+		return db.QueryRowContext(queryCtx, query, id).Scan(
+			&product.ID,
+			&product.Name,
+			&product.Type,
+			&product.Price,
+			&product.Description,
+			&product.ImageURL,
+			&product.Private,
+		)
+	})
+	tracing.End(span, queryError(err))
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("product with ID %d not found", id)
 	}
 	if err != nil {
-		log.Printf("Error getting product by ID: %v", err)
+		r.logger.ErrorContext(ctx, "getting product by ID failed", "product_id", id, "error", err)
 		return nil, err
 	}
 
@@ -102,22 +149,27 @@ func (r *PostgresProductRepository) GetByID(id int) (*models.Product, error) {
 }
 
 // Search implements models.ProductRepository.
-func (r *PostgresProductRepository) Search(name string) ([]models.Product, error) {
+func (r *PostgresProductRepository) Search(ctx context.Context, name string, includePrivate bool) (_ []models.Product, err error) {
+	defer metrics.ObserveQuery("products", "Search")()
+
 	// Remove special characters from search term into a new string
 	searchTerm := regexp.MustCompile(`[^a-zA-Z0-9]+`).ReplaceAllString(name, "")
 
-	query := `SELECT id, name, type, price, description, image_url 
+	query := `SELECT id, name, type, price, description, image_url, private 
 			  FROM products 
 			  WHERE regexp_replace(lower(name), '[^a-zA-Z0-9]+', '', 'g') 
-			  LIKE lower($1)`
+			  LIKE lower($1) AND ($2 OR NOT private)`
 	//the above converts name to lowercase, removes non-alphanumeric characters, and then compares it to the provided search term.
 
 	// Add wildcards for partial matching (matching any sequence of characters within name)
 	searchTerm = "%" + searchTerm + "%"
 
-	rows, err := r.db.Query(query, searchTerm)
+	queryCtx, span := tracing.StartQuery(ctx, "SELECT products", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := queryRead(queryCtx, r.reader(ctx), query, searchTerm, includePrivate)
 	if err != nil {
-		log.Printf("Error searching products: %v", err)
+		r.logger.ErrorContext(ctx, "searching products failed", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -132,9 +184,10 @@ func (r *PostgresProductRepository) Search(name string) ([]models.Product, error
 			&product.Price,
 			&product.Description,
 			&product.ImageURL,
+			&product.Private,
 		)
 		if err != nil {
-			log.Printf("Error scanning product row: %v", err)
+			r.logger.ErrorContext(ctx, "scanning product row failed", "error", err)
 			return nil, err
 		}
 		products = append(products, product)
@@ -148,65 +201,140 @@ func (r *PostgresProductRepository) Search(name string) ([]models.Product, error
 }
 
 // Update implements models.ProductRepository.
-func (r *PostgresProductRepository) Update(product *models.Product) error {
-	// Handle base64 image if present
-	if product.Image != "" {
-		imageURL, err := utils.SaveBase64Image(product.Image)
-		if err != nil {
-			log.Printf("Error saving image: %v", err)
-			return err
-		}
-		product.ImageURL = imageURL
+func (r *PostgresProductRepository) Update(ctx context.Context, product *models.Product) error {
+	defer metrics.ObserveQuery("products", "Update")()
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "starting transaction failed", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the row and keep the current version for the image reference,
+	// the revision and the audit entry
+	lockQuery := `SELECT id, name, type, price, description, image_url, private, created_at 
+		FROM products 
+		WHERE id = $1 
+		FOR UPDATE`
+	queryCtx, span := tracing.StartQuery(ctx, "SELECT products FOR UPDATE", lockQuery)
+	previous, err := scanProductRow(tx.QueryRowContext(queryCtx, lockQuery, product.ID))
+	tracing.End(span, queryError(err))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("product with ID %d not found", product.ID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "updating product failed", "product_id", product.ID, "error", err)
+		return err
 	}
 
 	query := `
 	UPDATE products
-	SET name = $1, type = $2, price = $3, description = $4, image_url = $5
-	WHERE id = $6
+	SET name = $1, type = $2, price = $3, description = $4, image_url = $5, private = $6
+	WHERE id = $7
 	`
 
-	result, err := r.db.Exec(
+	queryCtx, span = tracing.StartQuery(ctx, "UPDATE products", query)
+	_, err = tx.ExecContext(
+		queryCtx,
 		query,
 		product.Name,
 		product.Type,
 		product.Price,
 		product.Description,
 		product.ImageURL,
+		product.Private,
 		product.ID,
 	)
+	tracing.End(span, err)
 	if err != nil {
-		log.Printf("Error updating product: %v", err)
+		r.logger.ErrorContext(ctx, "updating product failed", "product_id", product.ID, "error", err)
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	if previous.ImageURL != product.ImageURL {
+		if err := adjustImageRef(ctx, tx.Tx, r.logger, previous.ImageURL, -1); err != nil {
+			return err
+		}
+		if err := adjustImageRef(ctx, tx.Tx, r.logger, product.ImageURL, 1); err != nil {
+			return err
+		}
+	}
+
+	if err := recordRevision(ctx, tx.Tx, r.logger, previous); err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("product with ID %d not found", product.ID)
+	product.CreatedAt = previous.CreatedAt
+	if err := recordAudit(ctx, tx.Tx, r.logger, product.ID, models.AuditActionUpdate, previous, product); err != nil {
+		return err
 	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	models.AfterCommit(ctx, func() { r.replicas.MarkWrite(ctx) })
 	return nil
 }
 
-func NewProductRepository(db *sql.DB) *PostgresProductRepository {
-	return &PostgresProductRepository{db: db}
+// scanProductRow reads a full product row, tolerating NULL description
+// and image_url.
+func scanProductRow(row rowScanner) (*models.Product, error) {
+	var product models.Product
+	var description, imageURL sql.NullString
+	err := row.Scan(
+		&product.ID,
+		&product.Name,
+		&product.Type,
+		&product.Price,
+		&description,
+		&imageURL,
+		&product.Private,
+		&product.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	product.Description = description.String
+	product.ImageURL = imageURL.String
+	return &product, nil
+}
+
+func NewProductRepository(db *sql.DB, replicas *ReplicaSet, logger *slog.Logger) *PostgresProductRepository {
+	return &PostgresProductRepository{db: db, replicas: replicas, logger: logger}
+}
+
+// reader is the connection for catalog reads, see ReplicaSet. Inside a
+// unit of work reads go through its transaction.
+func (r *PostgresProductRepository) reader(ctx context.Context) queryer {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	if replica := r.replicas.Reader(ctx); replica != nil {
+		return replica
+	}
+	return r.db
 }
 
-func (r *PostgresProductRepository) GetAll(page int, pageSize int) ([]models.Product, error) {
+func (r *PostgresProductRepository) GetAll(ctx context.Context, page int, pageSize int, includePrivate bool) (_ []models.Product, err error) {
+	defer metrics.ObserveQuery("products", "GetAll")()
+
 	// Calculate offset
 	offset := (page - 1) * pageSize
 
 	query := `
-        SELECT id, name, type, price, description, image_url 
+        SELECT id, name, type, price, description, image_url, private 
         FROM products 
+        WHERE $3 OR NOT private
         ORDER BY id 
         LIMIT $1 OFFSET $2`
 
-	rows, err := r.db.Query(query, pageSize, offset)
+	queryCtx, span := tracing.StartQuery(ctx, "SELECT products", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := queryRead(queryCtx, r.reader(ctx), query, pageSize, offset, includePrivate)
 	if err != nil {
-		log.Printf("Error getting products: %v", err)
+		r.logger.ErrorContext(ctx, "getting products failed", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -221,9 +349,10 @@ func (r *PostgresProductRepository) GetAll(page int, pageSize int) ([]models.Pro
 			&product.Price,
 			&product.Description,
 			&product.ImageURL,
+			&product.Private,
 		)
 		if err != nil {
-			log.Printf("Error scanning product row: %v", err)
+			r.logger.ErrorContext(ctx, "scanning product row failed", "error", err)
 			return nil, err
 		}
 		products = append(products, product)
@@ -237,10 +366,12 @@ func (r *PostgresProductRepository) GetAll(page int, pageSize int) ([]models.Pro
 }
 
 // repository/product_repository.go
-func (r *PostgresProductRepository) GetProducts(params models.FilterParams) (*models.PaginatedResponse, error) {
+func (r *PostgresProductRepository) GetProducts(ctx context.Context, params models.FilterParams) (_ *models.PaginatedResponse, err error) {
+	defer metrics.ObserveQuery("products", "GetProducts")()
+
 	// Build dynamic query
 	baseQuery := `
-        SELECT COUNT(*) OVER(), id, name, type, price, description, image_url, created_at 
+        SELECT COUNT(*) OVER(), id, name, type, price, description, image_url, private, created_at 
         FROM products 
         WHERE 1=1`
 
@@ -267,6 +398,10 @@ func (r *PostgresProductRepository) GetProducts(params models.FilterParams) (*mo
 		paramCount++
 	}
 
+	if !params.IncludePrivate {
+		baseQuery += " AND NOT private"
+	}
+
 	// // Add sorting
 	// if params.SortBy != "" {
 	// 	validColumns := map[string]bool{
@@ -322,9 +457,12 @@ func (r *PostgresProductRepository) GetProducts(params models.FilterParams) (*mo
 	queryParams = append(queryParams, params.PageSize, offset)
 
 	// Execute query
-	rows, err := r.db.Query(baseQuery, queryParams...)
+	queryCtx, span := tracing.StartQuery(ctx, "SELECT products", baseQuery)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := queryRead(queryCtx, r.reader(ctx), baseQuery, queryParams...)
 	if err != nil {
-		log.Printf("Error executing query: %v", err)
+		r.logger.ErrorContext(ctx, "executing query failed", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -342,10 +480,11 @@ func (r *PostgresProductRepository) GetProducts(params models.FilterParams) (*mo
 			&product.Price,
 			&product.Description,
 			&product.ImageURL,
+			&product.Private,
 			&product.CreatedAt,
 		)
 		if err != nil {
-			log.Printf("Error scanning product row: %v", err)
+			r.logger.ErrorContext(ctx, "scanning product row failed", "error", err)
 			return nil, err
 		}
 		products = append(products, product)
@@ -365,3 +504,85 @@ func (r *PostgresProductRepository) GetProducts(params models.FilterParams) (*mo
 		TotalPages: totalPages,
 	}, nil
 }
+
+// GetImageURLs implements models.ProductRepository.
+func (r *PostgresProductRepository) GetImageURLs(ctx context.Context) (_ []string, err error) {
+	defer metrics.ObserveQuery("products", "GetImageURLs")()
+
+	// Images of older revisions stay on disk so that they can be restored.
+	// Read from the primary, a lagging replica could miss an image that was
+	// just referenced and get it collected
+	query := `SELECT image_url FROM products
+	WHERE image_url IS NOT NULL AND image_url <> ''
+	UNION
+	SELECT image_url FROM product_revisions
+	WHERE image_url IS NOT NULL AND image_url <> ''`
+
+	queryCtx, span := tracing.StartQuery(ctx, "SELECT image_url", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := queryRead(queryCtx, r.db, query)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "getting image URLs failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			r.logger.ErrorContext(ctx, "scanning image URL failed", "error", err)
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// IsPrivateImage implements models.ProductRepository.
+func (r *PostgresProductRepository) IsPrivateImage(ctx context.Context, imageURL string) (bool, error) {
+	defer metrics.ObserveQuery("products", "IsPrivateImage")()
+
+	// The image of a public product is public, also when a private product
+	// shares the file or a draft revision used it before publishing; the
+	// product is returned with an unsigned URL. Otherwise an image replaced
+	// on a private product stays private with the product's revisions.
+	query := `SELECT NOT EXISTS (
+		SELECT 1 FROM products WHERE image_url = $1 AND NOT private
+	) AND (EXISTS (
+		SELECT 1 FROM products WHERE image_url = $1 AND private
+	) OR EXISTS (
+		SELECT 1 FROM product_revisions rev
+		JOIN products p ON p.id = rev.product_id
+		WHERE rev.image_url = $1 AND p.private
+	))`
+
+	queryCtx, span := tracing.StartQuery(ctx, "SELECT products", query)
+
+	var private bool
+	err := retryRead(queryCtx, func() error {
+		return r.db.QueryRowContext(queryCtx, query, imageURL).Scan(&private)
+	})
+	tracing.End(span, err)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "checking image visibility failed", "error", err)
+		return false, err
+	}
+
+	return private, nil
+}
+
+// queryError is the error to record on a statement span, a missing row is
+// an expected outcome.
+func queryError(err error) error {
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
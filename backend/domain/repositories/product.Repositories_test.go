@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/migrations"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// openTestDatabase connects to the Postgres database in TEST_DATABASE_URL
+// and migrates it. Tests needing it are skipped without one.
+func openTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := migrations.Run(db, discardLogger()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createTestProduct creates a product with imageURL, removed when the test
+// ends.
+func createTestProduct(t *testing.T, repo *PostgresProductRepository, imageURL string, private bool) *models.Product {
+	t.Helper()
+	product := &models.Product{Name: "Test product", Type: "test", Price: 1, ImageURL: imageURL, Private: private}
+	if err := repo.Create(context.Background(), product); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Delete(context.Background(), product.ID) })
+	return product
+}
+
+func assertPrivateImage(t *testing.T, repo *PostgresProductRepository, imageURL string, want bool) {
+	t.Helper()
+	private, err := repo.IsPrivateImage(context.Background(), imageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if private != want {
+		t.Errorf("IsPrivateImage(%q) = %t, want %t", imageURL, private, want)
+	}
+}
+
+func testImageURL() string {
+	return fmt.Sprintf("/uploads/test-%d.png", time.Now().UnixNano())
+}
+
+func TestIsPrivateImagePublishingDraft(t *testing.T) {
+	repo := NewProductRepository(openTestDatabase(t), nil, discardLogger())
+	imageURL := testImageURL()
+
+	product := createTestProduct(t, repo, imageURL, true)
+	assertPrivateImage(t, repo, imageURL, true)
+
+	// Publishing records the private draft as a revision with the same
+	// image, the published product is served with an unsigned URL
+	product.Private = false
+	if err := repo.Update(context.Background(), product); err != nil {
+		t.Fatal(err)
+	}
+	assertPrivateImage(t, repo, imageURL, false)
+
+	// Making it private again hides the image with it
+	product.Private = true
+	if err := repo.Update(context.Background(), product); err != nil {
+		t.Fatal(err)
+	}
+	assertPrivateImage(t, repo, imageURL, true)
+}
+
+func TestIsPrivateImageReplacedOnPrivateProduct(t *testing.T) {
+	repo := NewProductRepository(openTestDatabase(t), nil, discardLogger())
+	oldImageURL, newImageURL := testImageURL(), testImageURL()
+
+	product := createTestProduct(t, repo, oldImageURL, true)
+	product.ImageURL = newImageURL
+	if err := repo.Update(context.Background(), product); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old image is only referenced by a revision of the private product
+	assertPrivateImage(t, repo, oldImageURL, true)
+	assertPrivateImage(t, repo, newImageURL, true)
+}
+
+func TestIsPrivateImageSharedWithPublicProduct(t *testing.T) {
+	repo := NewProductRepository(openTestDatabase(t), nil, discardLogger())
+	imageURL := testImageURL()
+
+	createTestProduct(t, repo, imageURL, true)
+	public := createTestProduct(t, repo, imageURL, false)
+
+	// Deduplicated uploads share the file, the public product must be
+	// able to serve it
+	assertPrivateImage(t, repo, imageURL, false)
+
+	if err := repo.Delete(context.Background(), public.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertPrivateImage(t, repo, imageURL, true)
+}
+
+func TestIsPrivateImageUnknown(t *testing.T) {
+	repo := NewProductRepository(openTestDatabase(t), nil, discardLogger())
+	assertPrivateImage(t, repo, testImageURL(), false)
+}
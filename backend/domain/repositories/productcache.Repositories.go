@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	productCacheKey     = "products:id:"
+	productListCacheKey = "products:list:"
+)
+
+// CachedProductRepository caches GetByID and GetProducts of the wrapped
+// repository. Writes through it invalidate the product and every cached
+// page once committed, other methods go straight to the wrapped repository.
+//
+// Values are stored JSON encoded, callers always get their own copy and
+// may modify it (the controllers sign image URLs in place).
+type CachedProductRepository struct {
+	models.ProductRepository
+	cache  models.Cache
+	ttl    time.Duration
+	logger *slog.Logger
+
+	// Concurrent misses for the same key run a single query
+	group singleflight.Group
+
+	// generation changes on every invalidation, a load that started
+	// before a write must not store its now stale result
+	mu            sync.Mutex
+	generation    uint64
+	invalidatedAt time.Time
+
+	// Nothing is stored for this long after a write, reads served by a
+	// lagging replica could still return the old data
+	replicaLag time.Duration
+}
+
+func NewCachedProductRepository(next models.ProductRepository, cache models.Cache, ttl time.Duration, replicaLag time.Duration, logger *slog.Logger) *CachedProductRepository {
+	return &CachedProductRepository{ProductRepository: next, cache: cache, ttl: ttl, replicaLag: replicaLag, logger: logger}
+}
+
+// GetByID implements models.ProductRepository.
+func (r *CachedProductRepository) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	// A unit of work may hold writes the cache does not know about yet
+	if models.InUnitOfWork(ctx) {
+		return r.ProductRepository.GetByID(ctx, id)
+	}
+
+	var product models.Product
+	err := r.load(ctx, "product", productCacheKey+strconv.Itoa(id), &product, func(ctx context.Context) (interface{}, error) {
+		return r.ProductRepository.GetByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetProducts implements models.ProductRepository.
+func (r *CachedProductRepository) GetProducts(ctx context.Context, params models.FilterParams) (*models.PaginatedResponse, error) {
+	if models.InUnitOfWork(ctx) {
+		return r.ProductRepository.GetProducts(ctx, params)
+	}
+
+	// Every filter, sort and page combination is its own entry
+	key, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var page models.PaginatedResponse
+	err = r.load(ctx, "product_list", productListCacheKey+string(key), &page, func(ctx context.Context) (interface{}, error) {
+		return r.ProductRepository.GetProducts(ctx, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Create implements models.ProductRepository.
+func (r *CachedProductRepository) Create(ctx context.Context, product *models.Product) error {
+	if err := r.ProductRepository.Create(ctx, product); err != nil {
+		return err
+	}
+	models.AfterCommit(ctx, func() { r.invalidate(ctx) })
+	return nil
+}
+
+// Update implements models.ProductRepository.
+func (r *CachedProductRepository) Update(ctx context.Context, product *models.Product) error {
+	if err := r.ProductRepository.Update(ctx, product); err != nil {
+		return err
+	}
+	models.AfterCommit(ctx, func() { r.invalidate(ctx, product.ID) })
+	return nil
+}
+
+// Delete implements models.ProductRepository.
+func (r *CachedProductRepository) Delete(ctx context.Context, id int) error {
+	if err := r.ProductRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	models.AfterCommit(ctx, func() { r.invalidate(ctx, id) })
+	return nil
+}
+
+// load decodes the cached value of key into dst, or runs fetch and caches
+// its result. Cache failures are logged and fall back to the database.
+func (r *CachedProductRepository) load(ctx context.Context, name string, key string, dst interface{}, fetch func(context.Context) (interface{}, error)) error {
+	data, ok, err := r.cache.Get(ctx, key)
+	if err != nil {
+		r.logger.WarnContext(ctx, "reading product cache failed", "key", key, "error", err)
+	}
+	if ok && json.Unmarshal(data, dst) == nil {
+		metrics.CacheRequests.WithLabelValues(name, "hit").Inc()
+		return nil
+	}
+	metrics.CacheRequests.WithLabelValues(name, "miss").Inc()
+
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	// Callers arriving after an invalidation start a new query instead of
+	// joining one that may return the old data
+	flightKey := key + "@" + strconv.FormatUint(generation, 10)
+	value, err, _ := r.group.Do(flightKey, func() (interface{}, error) {
+		// The query is shared, one caller going away must not fail the others
+		result, err := fetch(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.generation == generation && time.Since(r.invalidatedAt) >= r.replicaLag {
+			if err := r.cache.Set(ctx, key, data, r.ttl); err != nil {
+				r.logger.WarnContext(ctx, "writing product cache failed", "key", key, "error", err)
+			}
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(value.([]byte), dst)
+}
+
+// invalidate drops the given products and every cached page, any write can
+// move a product in or out of a filtered page.
+func (r *CachedProductRepository) invalidate(ctx context.Context, ids ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	r.invalidatedAt = time.Now()
+
+	for _, id := range ids {
+		if err := r.cache.Delete(ctx, productCacheKey+strconv.Itoa(id)); err != nil {
+			r.logger.WarnContext(ctx, "invalidating product cache failed", "product_id", id, "error", err)
+		}
+	}
+	if err := r.cache.DeletePrefix(ctx, productListCacheKey); err != nil {
+		r.logger.WarnContext(ctx, "invalidating product cache failed", "error", err)
+	}
+}
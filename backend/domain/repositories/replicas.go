@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaSet routes catalog reads to read replicas. Replicas lag behind
+// the primary, so a client that just wrote keeps reading from the primary
+// for a short window and sees its own change. A nil or empty ReplicaSet
+// sends everything to the primary.
+type ReplicaSet struct {
+	replicas []*sql.DB
+	window   time.Duration
+	next     atomic.Uint64
+
+	mu         sync.Mutex
+	lastWrites map[string]time.Time
+}
+
+func NewReplicaSet(replicas []*sql.DB, window time.Duration) *ReplicaSet {
+	return &ReplicaSet{
+		replicas:   replicas,
+		window:     window,
+		lastWrites: make(map[string]time.Time),
+	}
+}
+
+// Reader returns the replica a read for the caller in ctx should use,
+// picked round robin, or nil when the read has to go to the primary.
+func (s *ReplicaSet) Reader(ctx context.Context) *sql.DB {
+	if s == nil || len(s.replicas) == 0 {
+		return nil
+	}
+
+	if key := writerKey(ctx); key != "" {
+		s.mu.Lock()
+		lastWrite, ok := s.lastWrites[key]
+		s.mu.Unlock()
+		if ok && time.Since(lastWrite) < s.window {
+			return nil
+		}
+	}
+
+	n := s.next.Add(1)
+	return s.replicas[n%uint64(len(s.replicas))]
+}
+
+// MarkWrite records that the caller in ctx committed a write, its reads
+// stay on the primary until the window has passed.
+func (s *ReplicaSet) MarkWrite(ctx context.Context) {
+	if s == nil || len(s.replicas) == 0 {
+		return
+	}
+	key := writerKey(ctx)
+	if key == "" {
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastWrites[key] = now
+
+	// Forget writers whose window has passed, the map stays as small as
+	// the number of recently active writers
+	for k, t := range s.lastWrites {
+		if now.Sub(t) >= s.window {
+			delete(s.lastWrites, k)
+		}
+	}
+}
+
+// writerKey identifies the client for read-your-writes. Anonymous callers
+// cannot write, so they always read from a replica.
+func writerKey(ctx context.Context) string {
+	principal := models.PrincipalFromContext(ctx)
+	if principal == nil {
+		return ""
+	}
+	return principal.AuthMethod + ":" + principal.Subject
+}
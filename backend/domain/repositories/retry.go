@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Idempotent reads are retried this many times in total when the
+// connection fails underneath them, e.g. after a Postgres restart or
+// failover. Writes are never retried, the first attempt may have committed.
+const (
+	readAttempts = 3
+	readBackoff  = 50 * time.Millisecond
+)
+
+// retryRead runs fn until it succeeds, fails with a non transient error or
+// the attempts are used up, backing off exponentially between attempts.
+func retryRead(ctx context.Context, fn func() error) error {
+	backoff := readBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == readAttempts || !IsTransient(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// IsTransient reports whether err is a connection level failure worth
+// retrying on a fresh connection, as opposed to an error in the query.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now, the server is starting up
+			return true
+		}
+		// Class 08, connection exception
+		return pqErr.Code.Class() == "08"
+	}
+
+	return false
+}
+
+// queryRead runs a read only query through retryRead.
+func queryRead(ctx context.Context, db queryer, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := retryRead(ctx, func() (err error) {
+		rows, err = db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
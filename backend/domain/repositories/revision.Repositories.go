@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"PRODUCT_LIST/tracing"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+type PostgresRevisionRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewRevisionRepository(db *sql.DB, logger *slog.Logger) *PostgresRevisionRepository {
+	return &PostgresRevisionRepository{db: db, logger: logger}
+}
+
+// recordRevision snapshots the previous version of a product inside the
+// transaction updating it. The caller holds the row lock of the product, so
+// numbering the revision from the current maximum is safe.
+func recordRevision(ctx context.Context, tx *sql.Tx, logger *slog.Logger, previous *models.Product) error {
+	createdBy := "anonymous"
+	if principal := models.PrincipalFromContext(ctx); principal != nil {
+		createdBy = principal.Subject
+	}
+
+	query := `
+	INSERT INTO product_revisions (product_id, revision, name, type, price, description, image_url, private, created_by)
+	SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6, $7, $8
+	FROM product_revisions
+	WHERE product_id = $1`
+
+	queryCtx, span := tracing.StartQuery(ctx, "INSERT product_revisions", query)
+	_, err := tx.ExecContext(
+		queryCtx,
+		query,
+		previous.ID,
+		previous.Name,
+		previous.Type,
+		previous.Price,
+		previous.Description,
+		previous.ImageURL,
+		previous.Private,
+		createdBy,
+	)
+	tracing.End(span, err)
+	if err != nil {
+		logger.ErrorContext(ctx, "recording product revision failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+const revisionColumns = `id, product_id, revision, name, type, price, description, image_url, private, created_by, created_at`
+
+func scanRevision(row rowScanner) (*models.ProductRevision, error) {
+	var revision models.ProductRevision
+	var description, imageURL sql.NullString
+
+	err := row.Scan(
+		&revision.ID,
+		&revision.ProductID,
+		&revision.Revision,
+		&revision.Product.Name,
+		&revision.Product.Type,
+		&revision.Product.Price,
+		&description,
+		&imageURL,
+		&revision.Product.Private,
+		&revision.CreatedBy,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Product.ID = revision.ProductID
+	revision.Product.Description = description.String
+	revision.Product.ImageURL = imageURL.String
+	return &revision, nil
+}
+
+// List implements models.RevisionRepository.
+func (r *PostgresRevisionRepository) List(ctx context.Context, productID int) ([]models.ProductRevision, error) {
+	defer metrics.ObserveQuery("product_revisions", "List")()
+
+	query := `SELECT ` + revisionColumns + `
+	FROM product_revisions
+	WHERE product_id = $1
+	ORDER BY revision DESC`
+
+	rows, err := queryRead(ctx, r.db, query, productID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "listing product revisions failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.ProductRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "scanning product revision failed", "error", err)
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Get implements models.RevisionRepository.
+func (r *PostgresRevisionRepository) Get(ctx context.Context, productID int, revision int) (*models.ProductRevision, error) {
+	defer metrics.ObserveQuery("product_revisions", "Get")()
+
+	query := `SELECT ` + revisionColumns + `
+	FROM product_revisions
+	WHERE product_id = $1 AND revision = $2`
+
+	rows, err := queryRead(ctx, r.db, query, productID, revision)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "getting product revision failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			r.logger.ErrorContext(ctx, "getting product revision failed", "error", err)
+			return nil, err
+		}
+		return nil, fmt.Errorf("revision %d of product %d not found", revision, productID)
+	}
+
+	result, err := scanRevision(rows)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "scanning product revision failed", "error", err)
+		return nil, err
+	}
+
+	return result, nil
+}
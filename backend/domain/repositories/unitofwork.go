@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PostgresUnitOfWork implements models.UnitOfWork on the primary.
+type PostgresUnitOfWork struct {
+	db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) *PostgresUnitOfWork {
+	return &PostgresUnitOfWork{db: db}
+}
+
+type txContextKey struct{}
+
+// Do implements models.UnitOfWork. A nested Do joins the transaction that
+// is already running, the outermost one decides whether it commits.
+func (u *PostgresUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, txContextKey{}, tx)
+	ctx, hooks := models.WithTxHooks(ctx)
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			hooks.RolledBack()
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		tx.Rollback()
+		hooks.RolledBack()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		hooks.RolledBack()
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	hooks.Committed()
+	return nil
+}
+
+// unitTx is a transaction a repository method writes in: its own, or the
+// one of the unit of work in the context. Commit and Rollback only act on
+// its own, the unit of work finishes a joined one.
+type unitTx struct {
+	*sql.Tx
+	joined bool
+}
+
+func beginTx(ctx context.Context, db *sql.DB) (*unitTx, error) {
+	if tx, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return &unitTx{Tx: tx, joined: true}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &unitTx{Tx: tx}, nil
+}
+
+func (t *unitTx) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *unitTx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// queryer is what reads need, satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txFromContext returns the transaction of the unit of work in ctx, reads
+// inside a unit of work see its uncommitted writes.
+func txFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*sql.Tx)
+	return tx, ok
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/metrics"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+type PostgresUploadUsageRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewUploadUsageRepository(db *sql.DB, logger *slog.Logger) *PostgresUploadUsageRepository {
+	return &PostgresUploadUsageRepository{db: db, logger: logger}
+}
+
+// Reserve implements models.UploadUsageRepository. The check and the
+// increment are one statement, so concurrent uploads cannot both slip under
+// the limit.
+func (r *PostgresUploadUsageRepository) Reserve(ctx context.Context, subject string, day time.Time, bytes int64, limit int64) (bool, int64, error) {
+	defer metrics.ObserveQuery("upload_usage", "Reserve")()
+
+	query := `
+	INSERT INTO upload_usage (subject, day, bytes)
+	SELECT $1, $2, $3 WHERE $3 <= $4
+	ON CONFLICT (subject, day)
+	DO UPDATE SET bytes = upload_usage.bytes + excluded.bytes
+	WHERE upload_usage.bytes + excluded.bytes <= $4
+	RETURNING bytes`
+
+	var used int64
+	err := r.db.QueryRowContext(ctx, query, subject, day, bytes, limit).Scan(&used)
+	if err == sql.ErrNoRows {
+		used, err = r.Get(ctx, subject, day)
+		return false, used, err
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "reserving upload quota failed", "error", err)
+		return false, 0, err
+	}
+
+	return true, used, nil
+}
+
+// Release implements models.UploadUsageRepository.
+func (r *PostgresUploadUsageRepository) Release(ctx context.Context, subject string, day time.Time, bytes int64) error {
+	defer metrics.ObserveQuery("upload_usage", "Release")()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE upload_usage SET bytes = GREATEST(bytes - $3, 0)
+	WHERE subject = $1 AND day = $2`, subject, day, bytes)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "releasing upload quota failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Get implements models.UploadUsageRepository.
+func (r *PostgresUploadUsageRepository) Get(ctx context.Context, subject string, day time.Time) (int64, error) {
+	defer metrics.ObserveQuery("upload_usage", "Get")()
+
+	var used int64
+	err := r.db.QueryRowContext(ctx, `SELECT bytes FROM upload_usage WHERE subject = $1 AND day = $2`, subject, day).Scan(&used)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "getting upload usage failed", "error", err)
+		return 0, err
+	}
+	return used, nil
+}
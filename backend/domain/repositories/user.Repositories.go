@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+)
+
+type PostgresUserRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewUserRepository(db *sql.DB, logger *slog.Logger) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db, logger: logger}
+}
+
+// Create implements models.UserRepository.
+func (r *PostgresUserRepository) Create(user *models.User) error {
+	defer metrics.ObserveQuery("users", "Create")()
+
+	query := `
+	INSERT INTO users (email, password_hash)
+	VALUES ($1, $2)
+	RETURNING id, role, created_at`
+
+	err := r.db.QueryRow(query, user.Email, user.PasswordHash).Scan(&user.ID, &user.Role, &user.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return fmt.Errorf("user with email %s already exists", user.Email)
+	}
+	if err != nil {
+		r.logger.Error("creating user failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetByID implements models.UserRepository.
+func (r *PostgresUserRepository) GetByID(id int) (*models.User, error) {
+	defer metrics.ObserveQuery("users", "GetByID")()
+
+	query := `SELECT id, email, password_hash, role, created_at 
+	FROM users 
+	WHERE id = $1`
+
+	user := &models.User{}
+	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user with ID %d not found", id)
+	}
+	if err != nil {
+		r.logger.Error("getting user by ID failed", "error", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetByEmail implements models.UserRepository.
+func (r *PostgresUserRepository) GetByEmail(email string) (*models.User, error) {
+	defer metrics.ObserveQuery("users", "GetByEmail")()
+
+	query := `SELECT id, email, password_hash, role, created_at 
+	FROM users 
+	WHERE lower(email) = lower($1)`
+
+	user := &models.User{}
+	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user with email %s not found", email)
+	}
+	if err != nil {
+		r.logger.Error("getting user by email failed", "error", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateRole implements models.UserRepository.
+func (r *PostgresUserRepository) UpdateRole(id int, role string) error {
+	defer metrics.ObserveQuery("users", "UpdateRole")()
+
+	result, err := r.db.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, id)
+	if err != nil {
+		r.logger.Error("updating user role failed", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// CreateRefreshToken implements models.UserRepository.
+func (r *PostgresUserRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	defer metrics.ObserveQuery("users", "CreateRefreshToken")()
+
+	query := `
+	INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id`
+
+	err := r.db.QueryRow(query, token.UserID, token.TokenHash, token.FamilyID, token.ExpiresAt).Scan(&token.ID)
+	if err != nil {
+		r.logger.Error("creating refresh token failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetRefreshToken implements models.UserRepository.
+func (r *PostgresUserRepository) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	defer metrics.ObserveQuery("users", "GetRefreshToken")()
+
+	query := `SELECT id, user_id, token_hash, family_id, expires_at, revoked_at 
+	FROM refresh_tokens 
+	WHERE token_hash = $1`
+
+	token := &models.RefreshToken{}
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.FamilyID,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("refresh token not found")
+	}
+	if err != nil {
+		r.logger.Error("getting refresh token failed", "error", err)
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// RevokeRefreshToken implements models.UserRepository.
+// It returns an error if the token was already revoked, so that two
+// concurrent refreshes with the same token cannot both succeed.
+func (r *PostgresUserRepository) RevokeRefreshToken(id int) error {
+	defer metrics.ObserveQuery("users", "RevokeRefreshToken")()
+
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		r.logger.Error("revoking refresh token failed", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("refresh token already revoked")
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokenFamily implements models.UserRepository.
+func (r *PostgresUserRepository) RevokeRefreshTokenFamily(familyID string) error {
+	defer metrics.ObserveQuery("users", "RevokeRefreshTokenFamily")()
+
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.Exec(query, familyID); err != nil {
+		r.logger.Error("revoking refresh token family failed", "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,364 @@
+package repositories
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+)
+
+type PostgresWebhookRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewWebhookRepository(db *sql.DB, logger *slog.Logger) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{db: db, logger: logger}
+}
+
+const webhookSubscriptionColumns = `id, url, secret, event_types, active, created_by, created_at`
+
+func scanWebhookSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.URL,
+		&subscription.Secret,
+		pq.Array(&subscription.EventTypes),
+		&subscription.Active,
+		&subscription.CreatedBy,
+		&subscription.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// CreateSubscription implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	defer metrics.ObserveQuery("webhook_subscriptions", "CreateSubscription")()
+
+	query := `
+	INSERT INTO webhook_subscriptions (url, secret, event_types, active, created_by)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		subscription.URL,
+		subscription.Secret,
+		pq.Array(subscription.EventTypes),
+		subscription.Active,
+		subscription.CreatedBy,
+	).Scan(&subscription.ID, &subscription.CreatedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "creating webhook subscription failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListSubscriptions implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	defer metrics.ObserveQuery("webhook_subscriptions", "ListSubscriptions")()
+
+	return r.querySubscriptions(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY id`)
+}
+
+// SubscriptionsFor implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) SubscriptionsFor(ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	defer metrics.ObserveQuery("webhook_subscriptions", "SubscriptionsFor")()
+
+	return r.querySubscriptions(ctx, `SELECT `+webhookSubscriptionColumns+`
+	FROM webhook_subscriptions
+	WHERE active AND $1 = ANY(event_types)
+	ORDER BY id`, eventType)
+}
+
+func (r *PostgresWebhookRepository) querySubscriptions(ctx context.Context, query string, args ...interface{}) ([]models.WebhookSubscription, error) {
+	rows, err := queryRead(ctx, r.db, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "listing webhook subscriptions failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "scanning webhook subscription row failed", "error", err)
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// GetSubscription implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	defer metrics.ObserveQuery("webhook_subscriptions", "GetSubscription")()
+
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+
+	subscription, err := scanWebhookSubscription(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook subscription %d not found", id)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "getting webhook subscription failed", "error", err)
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// UpdateSubscription implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	defer metrics.ObserveQuery("webhook_subscriptions", "UpdateSubscription")()
+
+	query := `
+	UPDATE webhook_subscriptions
+	SET url = $2, secret = $3, event_types = $4, active = $5
+	WHERE id = $1
+	RETURNING created_by, created_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		subscription.ID,
+		subscription.URL,
+		subscription.Secret,
+		pq.Array(subscription.EventTypes),
+		subscription.Active,
+	).Scan(&subscription.CreatedBy, &subscription.CreatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("webhook subscription %d not found", subscription.ID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "updating webhook subscription failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteSubscription implements models.WebhookRepository. The delivery log
+// of the subscription goes with it.
+func (r *PostgresWebhookRepository) DeleteSubscription(ctx context.Context, id int) error {
+	defer metrics.ObserveQuery("webhook_subscriptions", "DeleteSubscription")()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "deleting webhook subscription failed", "error", err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("webhook subscription %d not found", id)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	response_status, response_body, last_error, duration_ms, created_at, delivered_at`
+
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload []byte
+	var responseStatus, durationMs sql.NullInt64
+	var responseBody, lastError sql.NullString
+	var deliveredAt sql.NullTime
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&responseStatus,
+		&responseBody,
+		&lastError,
+		&durationMs,
+		&delivery.CreatedAt,
+		&deliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = payload
+	delivery.ResponseBody = responseBody.String
+	delivery.LastError = lastError.String
+	if responseStatus.Valid {
+		status := int(responseStatus.Int64)
+		delivery.ResponseStatus = &status
+	}
+	if durationMs.Valid {
+		duration := int(durationMs.Int64)
+		delivery.DurationMs = &duration
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
+
+// CreateDelivery implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) (bool, error) {
+	defer metrics.ObserveQuery("webhook_deliveries", "CreateDelivery")()
+
+	var conn queryer = r.db
+	if tx, ok := txFromContext(ctx); ok {
+		conn = tx
+	}
+
+	query := `
+	INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (subscription_id, event_id) DO NOTHING
+	RETURNING id, status, created_at`
+
+	err := conn.QueryRowContext(ctx, query, delivery.SubscriptionID, delivery.EventID, delivery.EventType, []byte(delivery.Payload)).
+		Scan(&delivery.ID, &delivery.Status, &delivery.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "creating webhook delivery failed", "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// GetDelivery implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	defer metrics.ObserveQuery("webhook_deliveries", "GetDelivery")()
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook delivery %d not found", id)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "getting webhook delivery failed", "error", err)
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ListDeliveries implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) ListDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) (*models.WebhookDeliveryPage, error) {
+	defer metrics.ObserveQuery("webhook_deliveries", "ListDeliveries")()
+
+	baseQuery := `SELECT COUNT(*) OVER(), ` + webhookDeliveryColumns + `
+	FROM webhook_deliveries
+	WHERE subscription_id = $1`
+
+	queryParams := []interface{}{filter.SubscriptionID}
+	paramCount := 2
+
+	if filter.Status != "" {
+		baseQuery += fmt.Sprintf(" AND status = $%d", paramCount)
+		queryParams = append(queryParams, filter.Status)
+		paramCount++
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	baseQuery += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+	queryParams = append(queryParams, filter.PageSize, offset)
+
+	rows, err := queryRead(ctx, r.db, baseQuery, queryParams...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "listing webhook deliveries failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	var total int
+
+	for rows.Next() {
+		var delivery *models.WebhookDelivery
+		delivery, err = scanWebhookDelivery(totalScanner{rows, &total})
+		if err != nil {
+			r.logger.ErrorContext(ctx, "scanning webhook delivery row failed", "error", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookDeliveryPage{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalPages: (total + filter.PageSize - 1) / filter.PageSize,
+	}, nil
+}
+
+// RecordAttempt implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	defer metrics.ObserveQuery("webhook_deliveries", "RecordAttempt")()
+
+	query := `
+	UPDATE webhook_deliveries
+	SET status = $2, attempts = attempts + 1, response_status = $3, response_body = NULLIF($4, ''),
+		last_error = NULLIF($5, ''), duration_ms = $6, delivered_at = $7
+	WHERE id = $1
+	RETURNING attempts`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		delivery.ID,
+		delivery.Status,
+		delivery.ResponseStatus,
+		delivery.ResponseBody,
+		delivery.LastError,
+		delivery.DurationMs,
+		delivery.DeliveredAt,
+	).Scan(&delivery.Attempts)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("webhook delivery %d not found", delivery.ID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "recording webhook delivery attempt failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ResetDelivery implements models.WebhookRepository.
+func (r *PostgresWebhookRepository) ResetDelivery(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("webhook_deliveries", "ResetDelivery")()
+
+	var conn queryer = r.db
+	if tx, ok := txFromContext(ctx); ok {
+		conn = tx
+	}
+
+	err := conn.QueryRowContext(ctx, `
+	UPDATE webhook_deliveries
+	SET status = 'pending'
+	WHERE id = $1 AND status = 'failed'
+	RETURNING id`, id).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("failed webhook delivery %d not found", id)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "resetting webhook delivery failed", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/utils"
+	"crypto/rsa"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// APIKeyAuthenticator resolves an X-API-Key header to its principal.
+type APIKeyAuthenticator interface {
+	Authenticate(key string) (*models.Principal, error)
+}
+
+type AuthOptions struct {
+	// HMACSecret validates HS256 tokens
+	HMACSecret string
+	// JWKSFile is a local JSON Web Key Set used to validate RS256 tokens
+	JWKSFile string
+	Issuer   string
+	Audience string
+	// PublicReads lets anonymous clients use GET and HEAD routes
+	PublicReads bool
+	// PublicPaths are path prefixes open to anonymous clients for any method
+	PublicPaths []string
+	// APIKeys validates keys sent in the X-API-Key header
+	APIKeys APIKeyAuthenticator
+	// ClientCertRole is the role of callers presenting a verified TLS client
+	// certificate, empty ignores client certificates
+	ClientCertRole string
+}
+
+// Authenticator validates bearer tokens or API keys and attaches the principal to the
+// request context. Anonymous requests are only let through for reads, and
+// only when PublicReads is enabled.
+type Authenticator struct {
+	options AuthOptions
+	rsaKeys map[string]*rsa.PublicKey
+	parser  *jwt.Parser
+}
+
+func NewAuthenticator(options AuthOptions, logger *slog.Logger) (*Authenticator, error) {
+	a := &Authenticator{options: options}
+
+	methods := []string{}
+	if options.HMACSecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if options.JWKSFile != "" {
+		keys, err := loadJWKS(options.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		logger.Warn("no JWT secret or JWKS file configured, every write request will be rejected")
+	}
+
+	parserOptions := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+	a.parser = jwt.NewParser(parserOptions...)
+
+	return a, nil
+}
+
+// Middleware is meant to be installed with router.Use.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("X-API-Key"); key != "" && a.options.APIKeys != nil {
+			principal, err := a.options.APIKeys.Authenticate(key)
+			if err != nil {
+				unauthorized(w, "Invalid API key")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(models.WithPrincipal(r.Context(), principal)))
+			return
+		}
+
+		token, hasToken := bearerToken(r)
+
+		if !hasToken {
+			if principal := a.clientCertPrincipal(r); principal != nil {
+				next.ServeHTTP(w, r.WithContext(models.WithPrincipal(r.Context(), principal)))
+				return
+			}
+			if a.options.PublicReads && isRead(r) || a.isPublicPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			unauthorized(w, "Authentication required")
+			return
+		}
+
+		principal, err := a.Authenticate(token)
+		if err != nil {
+			unauthorized(w, "Invalid token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(models.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Authenticate validates a raw JWT and returns its principal.
+func (a *Authenticator) Authenticate(raw string) (*models.Principal, error) {
+	claims := &utils.AccessClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.keyFunc); err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	return &models.Principal{
+		Subject:    claims.Subject,
+		Role:       claims.Role,
+		AuthMethod: "jwt",
+	}, nil
+}
+
+func (a *Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return []byte(a.options.HMACSecret), nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		// Tokens without kid are accepted when the set holds a single key
+		if kid == "" && len(a.rsaKeys) == 1 {
+			for _, key := range a.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+// clientCertPrincipal returns the caller identified by a client certificate
+// the TLS handshake verified against the configured CA.
+func (a *Authenticator) clientCertPrincipal(r *http.Request) *models.Principal {
+	if a.options.ClientCertRole == "" || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	return &models.Principal{
+		Subject:    cert.Subject.CommonName,
+		Role:       a.options.ClientCertRole,
+		AuthMethod: "mtls",
+	}
+}
+
+func (a *Authenticator) isPublicPath(path string) bool {
+	for _, prefix := range a.options.PublicPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func isRead(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	http.Error(w, message, http.StatusUnauthorized)
+}
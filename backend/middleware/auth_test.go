@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/utils"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+var testTokenOptions = utils.AccessTokenOptions{
+	Secret:   "test-secret",
+	Issuer:   "product-list",
+	Audience: "product-list-api",
+	TTL:      time.Minute,
+}
+
+func newTestAuthenticator(t *testing.T, publicReads bool) *Authenticator {
+	t.Helper()
+	authenticator, err := NewAuthenticator(AuthOptions{
+		HMACSecret:  testTokenOptions.Secret,
+		Issuer:      testTokenOptions.Issuer,
+		Audience:    testTokenOptions.Audience,
+		PublicReads: publicReads,
+		PublicPaths: []string{"/api/auth/"},
+	}, discardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator
+}
+
+func issueToken(t *testing.T, options utils.AccessTokenOptions) string {
+	t.Helper()
+	token, _, err := utils.IssueAccessToken(options, "42", models.RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthenticatorMiddleware(t *testing.T) {
+	otherSecret := testTokenOptions
+	otherSecret.Secret = "other-secret"
+	otherAudience := testTokenOptions
+	otherAudience.Audience = "another-api"
+	expired := testTokenOptions
+	expired.TTL = -time.Minute
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		publicReads   bool
+		wantStatus    int
+		wantSubject   string
+	}{
+		{"valid token", http.MethodPost, "/api/products", "Bearer " + issueToken(t, testTokenOptions), false, http.StatusOK, "42"},
+		{"lowercase scheme", http.MethodPost, "/api/products", "bearer " + issueToken(t, testTokenOptions), false, http.StatusOK, "42"},
+		{"wrong secret", http.MethodPost, "/api/products", "Bearer " + issueToken(t, otherSecret), false, http.StatusUnauthorized, ""},
+		{"wrong audience", http.MethodPost, "/api/products", "Bearer " + issueToken(t, otherAudience), false, http.StatusUnauthorized, ""},
+		{"expired token", http.MethodPost, "/api/products", "Bearer " + issueToken(t, expired), false, http.StatusUnauthorized, ""},
+		{"garbage token", http.MethodPost, "/api/products", "Bearer not.a.jwt", false, http.StatusUnauthorized, ""},
+		{"anonymous write", http.MethodPost, "/api/products", "", true, http.StatusUnauthorized, ""},
+		{"anonymous read", http.MethodGet, "/api/products", "", true, http.StatusOK, ""},
+		{"anonymous read without public reads", http.MethodGet, "/api/products", "", false, http.StatusUnauthorized, ""},
+		{"anonymous public path", http.MethodPost, "/api/auth/login", "", false, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			handler := newTestAuthenticator(t, tt.publicReads).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if principal := models.PrincipalFromContext(r.Context()); principal != nil {
+					subject = principal.Subject
+				}
+			}))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestAuthenticateRejectsTokensWithoutSubject(t *testing.T) {
+	token, _, err := utils.IssueAccessToken(testTokenOptions, "", models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestAuthenticator(t, false).Authenticate(token); err == nil {
+		t.Fatal("accepted a token without subject")
+	}
+}
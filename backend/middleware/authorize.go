@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/services"
+	"context"
+	"net/http"
+)
+
+// Authorizer enforces the permission a route requires against the role of
+// the authenticated principal. With publicReads, anonymous clients may still
+// use the catalog read routes.
+type Authorizer struct {
+	policy      *services.PolicyService
+	publicReads bool
+}
+
+func NewAuthorizer(policy *services.PolicyService, publicReads bool) *Authorizer {
+	return &Authorizer{policy: policy, publicReads: publicReads}
+}
+
+// Require wraps handler so that it only runs for principals holding permission.
+func (a *Authorizer) Require(permission string, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := models.PrincipalFromContext(r.Context())
+		if principal == nil {
+			if a.publicReads && permission == models.PermissionProductsRead {
+				handler(w, r)
+				return
+			}
+			unauthorized(w, "Authentication required")
+			return
+		}
+
+		if !a.allowed(principal, permission) {
+			http.Error(w, "Forbidden: missing permission "+permission, http.StatusForbidden)
+			return
+		}
+
+		handler(w, r)
+	})
+}
+
+// Allowed reports whether the principal of ctx holds permission, for
+// handlers whose response depends on more than the route permission.
+func (a *Authorizer) Allowed(ctx context.Context, permission string) bool {
+	principal := models.PrincipalFromContext(ctx)
+	return principal != nil && a.allowed(principal, permission)
+}
+
+func (a *Authorizer) allowed(principal *models.Principal, permission string) bool {
+	// API keys carry scopes instead of a role
+	if principal.AuthMethod == "api_key" {
+		scope := models.ScopeForPermission(permission)
+		return scope != "" && principal.HasScope(scope)
+	}
+	return a.policy.Allowed(principal.Role, permission)
+}
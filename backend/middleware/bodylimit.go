@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// BodyLimiter caps request bodies with http.MaxBytesReader. Routes are
+// keyed by method and path template, e.g. "POST /api/products"; every other
+// route gets the default limit.
+type BodyLimiter struct {
+	defaultLimit int64
+	routes       map[string]int64
+}
+
+func NewBodyLimiter(defaultLimit int64, routes map[string]int64) *BodyLimiter {
+	return &BodyLimiter{defaultLimit: defaultLimit, routes: routes}
+}
+
+func (l *BodyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := l.limit(r)
+		if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Reject declared oversized bodies before reading anything
+		if r.ContentLength > limit {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *BodyLimiter) limit(r *http.Request) int64 {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			if limit, ok := l.routes[r.Method+" "+template]; ok {
+				return limit
+			}
+		}
+	}
+	return l.defaultLimit
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rs/cors"
+	"golang.org/x/net/publicsuffix"
+)
+
+type CORSOptions struct {
+	// AllowedOrigins are exact origins or subdomain patterns with a leading
+	// wildcard label, e.g. "https://*.example.com"
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// NewCORS builds the CORS handler from configuration. Credentials are
+// refused together with an allow-all origin, which would let any site act
+// with the user's cookies and tokens.
+func NewCORS(opts CORSOptions) (*cors.Cors, error) {
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			if opts.AllowCredentials {
+				return nil, fmt.Errorf("CORS origin * cannot be combined with credentials")
+			}
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return nil, fmt.Errorf("CORS origin %q must start with http:// or https://", origin)
+		}
+		if strings.Contains(origin, "*") {
+			if err := validateWildcardOrigin(origin); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return cors.New(cors.Options{
+		AllowedOrigins: opts.AllowedOrigins,
+		AllowedMethods: opts.AllowedMethods,
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata"},
+		ExposedHeaders: []string{"Content-Length", "X-Request-ID", "Location", "Tus-Resumable", "Upload-Length", "Upload-Offset",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
+		AllowCredentials: opts.AllowCredentials,
+		MaxAge:           int(opts.MaxAge.Seconds()),
+	}), nil
+}
+
+// validateWildcardOrigin only accepts a wildcard as the leading "*." label
+// of a host at or below a registrable domain. rs/cors matches the wildcard
+// against any text, "https://*" would allow every site and
+// "https://*example.com" would allow evilexample.com.
+func validateWildcardOrigin(origin string) error {
+	scheme, rest, _ := strings.Cut(origin, "://")
+	host, ok := strings.CutPrefix(rest, "*.")
+	if !ok || strings.Contains(host, "*") {
+		return fmt.Errorf("CORS origin %q may only use a wildcard as its first label, e.g. %s://*.example.com", origin, scheme)
+	}
+
+	hostname := host
+	if h, port, err := net.SplitHostPort(host); err == nil && port != "" {
+		hostname = h
+	}
+	if _, err := publicsuffix.EffectiveTLDPlusOne(hostname); err != nil || strings.ContainsAny(hostname, "/?#") {
+		return fmt.Errorf("CORS origin %q must have a registrable domain after the wildcard", origin)
+	}
+	return nil
+}
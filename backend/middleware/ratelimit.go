@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit allows Requests per Period, with bursts of up to Requests.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when
+	// the request was allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets. MemoryRateLimitStore serves a
+// single instance; a shared store (Redis, Postgres) lets several instances
+// enforce one limit.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit) (RateLimitResult, error)
+}
+
+type RateLimitOptions struct {
+	Read   RateLimit
+	Write  RateLimit
+	Upload RateLimit
+	// TrustedProxies are the addresses whose X-Forwarded-For header is
+	// believed, as IPs or CIDR ranges
+	TrustedProxies []string
+	Store          RateLimitStore
+}
+
+// RateLimiter throttles each client with separate buckets for reads, writes
+// and uploads. Clients are identified by API key, then user, then IP.
+type RateLimiter struct {
+	limits         map[string]RateLimit
+	trustedProxies []*net.IPNet
+	store          RateLimitStore
+	logger         *slog.Logger
+}
+
+func NewRateLimiter(opts RateLimitOptions, logger *slog.Logger) (*RateLimiter, error) {
+	limiter := &RateLimiter{
+		limits: map[string]RateLimit{
+			"read":   opts.Read,
+			"write":  opts.Write,
+			"upload": opts.Upload,
+		},
+		store:  opts.Store,
+		logger: logger,
+	}
+	if limiter.store == nil {
+		limiter.store = NewMemoryRateLimitStore()
+	}
+
+	for _, proxy := range opts.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", proxy, err)
+		}
+		limiter.trustedProxies = append(limiter.trustedProxies, network)
+	}
+
+	return limiter, nil
+}
+
+// Middleware must run after authentication so that API keys and users get
+// their own buckets.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := requestClass(r)
+		limit := l.limits[class]
+		if limit.Requests <= 0 || limit.Period <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result, err := l.store.Take(class+":"+l.clientKey(r), limit)
+		if err != nil {
+			// Fail open, an unavailable store must not take the API down
+			l.logger.ErrorContext(r.Context(), "rate limit store failed", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requestClass(r *http.Request) string {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/uploads"):
+		return "upload"
+	case isRead(r):
+		return "read"
+	default:
+		return "write"
+	}
+}
+
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	if principal := models.PrincipalFromContext(r.Context()); principal != nil {
+		if principal.AuthMethod == "api_key" {
+			return "key:" + principal.Subject
+		}
+		return "user:" + principal.Subject
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP is the remote address, or when that is a trusted proxy, the
+// right-most X-Forwarded-For entry that is not itself a trusted proxy.
+// Entries left of it are set by the client and cannot be believed.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !l.isTrustedProxy(ip) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+		if !l.isTrustedProxy(hop) {
+			return hop.String()
+		}
+	}
+
+	return host
+}
+
+func (l *RateLimiter) isTrustedProxy(ip net.IP) bool {
+	for _, network := range l.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	// period is the refill time of the limit the bucket was last taken from
+	period time.Duration
+}
+
+// rateLimitSweepInterval is how often MemoryRateLimitStore looks for idle
+// buckets.
+const rateLimitSweepInterval = time.Minute
+
+// MemoryRateLimitStore keeps token buckets in process memory. Buckets that
+// have refilled completely are dropped during periodic sweeps.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), lastSweep: time.Now()}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	capacity := float64(limit.Requests)
+	perToken := limit.Period / time.Duration(limit.Requests)
+
+	if now.Sub(s.lastSweep) > rateLimitSweepInterval {
+		s.sweep(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		s.buckets[key] = bucket
+	} else {
+		refill := float64(now.Sub(bucket.updated)) / float64(perToken)
+		bucket.tokens = math.Min(capacity, bucket.tokens+refill)
+		bucket.updated = now
+	}
+	bucket.period = limit.Period
+
+	result := RateLimitResult{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) * float64(perToken))
+	}
+
+	result.Remaining = int(bucket.tokens)
+	result.Reset = time.Duration((capacity - bucket.tokens) * float64(perToken))
+	return result, nil
+}
+
+// sweep drops buckets idle for longer than a full refill of their own
+// limit, they would be recreated full anyway.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) > bucket.period {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
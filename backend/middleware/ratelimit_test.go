@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Requests: 3, Period: time.Minute}
+
+	// A new client gets a full bucket
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take("client", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("request %d: allowed %t remaining %d, want allowed with %d remaining", 3-i, result.Allowed, result.Remaining, i)
+		}
+	}
+
+	result, _ := store.Take("client", limit)
+	if result.Allowed {
+		t.Fatal("request over the limit allowed")
+	}
+	// One token comes back every 20s
+	if result.RetryAfter <= 0 || result.RetryAfter > 20*time.Second {
+		t.Errorf("RetryAfter = %s, want up to 20s", result.RetryAfter)
+	}
+	if result.Reset <= 40*time.Second || result.Reset > time.Minute {
+		t.Errorf("Reset = %s, want close to a full period", result.Reset)
+	}
+
+	// Buckets are per key
+	if result, _ := store.Take("other", limit); !result.Allowed {
+		t.Error("another client was throttled")
+	}
+}
+
+func TestMemoryRateLimitStoreRefills(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Requests: 2, Period: time.Minute}
+
+	store.Take("client", limit)
+	store.Take("client", limit)
+
+	// Half a period later one token is back, not more
+	store.buckets["client"].updated = store.buckets["client"].updated.Add(-30 * time.Second)
+	if result, _ := store.Take("client", limit); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("after half a period: allowed %t remaining %d, want one token", result.Allowed, result.Remaining)
+	}
+	if result, _ := store.Take("client", limit); result.Allowed {
+		t.Fatal("bucket refilled more than one token")
+	}
+
+	// Idle for ages, the bucket is full but never above capacity
+	store.buckets["client"].updated = store.buckets["client"].updated.Add(-time.Hour)
+	if result, _ := store.Take("client", limit); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("after an hour: allowed %t remaining %d, want capacity minus one", result.Allowed, result.Remaining)
+	}
+}
+
+func TestMemoryRateLimitStoreSweepUsesBucketPeriod(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	store.Take("upload:client", RateLimit{Requests: 1, Period: time.Hour})
+	store.Take("read:client", RateLimit{Requests: 1, Period: time.Second})
+
+	now := time.Now().Add(2 * time.Minute)
+	store.sweep(now)
+
+	// The exhausted hourly bucket must survive, dropping it would hand the
+	// client a fresh one
+	if _, ok := store.buckets["upload:client"]; !ok {
+		t.Error("bucket with a long period was swept before it refilled")
+	}
+	if _, ok := store.buckets["read:client"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(string, RateLimit) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitOptions{
+		Read:  RateLimit{Requests: 2, Period: time.Minute},
+		Write: RateLimit{Requests: 1, Period: time.Minute},
+	}, discardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(method string, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/products", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := request(http.MethodGet, "192.0.2.1:1234")
+	if first.Code != http.StatusOK || first.Header().Get("RateLimit-Limit") != "2" || first.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("first read: %d limit %q remaining %q", first.Code, first.Header().Get("RateLimit-Limit"), first.Header().Get("RateLimit-Remaining"))
+	}
+	request(http.MethodGet, "192.0.2.1:1234")
+
+	throttled := request(http.MethodGet, "192.0.2.1:5678")
+	if throttled.Code != http.StatusTooManyRequests {
+		t.Fatalf("third read: status %d, want 429", throttled.Code)
+	}
+	if throttled.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+
+	// Reads and writes have separate buckets, clients their own
+	if rec := request(http.MethodPost, "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("write after reads: status %d, want 200", rec.Code)
+	}
+	if rec := request(http.MethodGet, "192.0.2.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("read of another client: status %d, want 200", rec.Code)
+	}
+}
+
+func TestRateLimiterKeysAuthenticatedClients(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitOptions{Read: RateLimit{Requests: 1, Period: time.Minute}}, discardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Two users behind the same address do not share a bucket
+	for _, subject := range []string{"1", "2"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req = req.WithContext(models.WithPrincipal(req.Context(), &models.Principal{Subject: subject, AuthMethod: "jwt"}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("user %s: status %d, want 200", subject, rec.Code)
+		}
+	}
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitOptions{
+		Read:  RateLimit{Requests: 1, Period: time.Minute},
+		Store: failingRateLimitStore{},
+	}, discardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/products", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status %d with an unavailable store, want 200", rec.Code)
+	}
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitOptions{TrustedProxies: []string{"10.0.0.0/8"}}, discardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct client", "192.0.2.1:1234", "", "192.0.2.1"},
+		{"untrusted proxy is ignored", "192.0.2.1:1234", "198.51.100.7", "192.0.2.1"},
+		{"trusted proxy", "10.0.0.1:1234", "198.51.100.7", "198.51.100.7"},
+		{"spoofed left entries", "10.0.0.1:1234", "203.0.113.9, 198.51.100.7, 10.0.0.2", "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := limiter.clientIP(req); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewRateLimiter(RateLimitOptions{TrustedProxies: []string{"not-an-ip"}}, discardLogger()); err == nil {
+		t.Error("accepted an invalid trusted proxy")
+	}
+}
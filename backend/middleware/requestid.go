@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const maxRequestIDLength = 128
+
+// RequestID propagates the X-Request-ID header of the caller, or assigns a
+// new one, and makes it available through the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+		next.ServeHTTP(w, r.WithContext(models.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
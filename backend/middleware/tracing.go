@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span per request, continuing the trace of the
+// caller when a W3C traceparent header is present. Spans are named after
+// the route template.
+func Tracing(router *mux.Router) func(http.Handler) http.Handler {
+	tracer := otel.Tracer("PRODUCT_LIST")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := routeTemplate(router, r)
+			ctx, span := tracer.Start(ctx, metricMethod(r.Method)+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+					semconv.ClientAddress(r.RemoteAddr),
+				),
+			)
+			defer span.End()
+
+			if requestID := models.RequestIDFromContext(ctx); requestID != "" {
+				span.SetAttributes(attribute.StringSlice("http.request.header.x-request-id", []string{requestID}))
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			span.SetAttributes(
+				semconv.HTTPResponseStatusCode(recorder.status),
+				semconv.HTTPResponseBodySize(int(recorder.bytes)),
+			)
+			if recorder.status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(recorder.status))
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+-- Baseline schema, matches controllers/products_table.pgsql plus created_at.
+CREATE TABLE IF NOT EXISTS products
+(
+    id SERIAL primary key,
+    name varchar(255) not null,
+    type varchar(255) not null,
+    price numeric(10,2) not null,
+    description text,
+    image_url text
+);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS created_at timestamptz not null default now();
@@ -0,0 +1,2 @@
+-- Private products (e.g. unreleased drafts) only serve their image through signed URLs.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS private boolean not null default false;
@@ -0,0 +1,13 @@
+-- Content-addressed images shared between products, reference counted.
+CREATE TABLE IF NOT EXISTS images
+(
+    image_url text primary key,
+    ref_count integer not null default 0,
+    created_at timestamptz not null default now()
+);
+
+INSERT INTO images (image_url, ref_count)
+SELECT image_url, COUNT(*) FROM products
+WHERE image_url IS NOT NULL AND image_url <> ''
+GROUP BY image_url
+ON CONFLICT (image_url) DO NOTHING;
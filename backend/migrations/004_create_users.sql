@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS users
+(
+    id SERIAL primary key,
+    email varchar(255) not null unique,
+    password_hash text not null,
+    created_at timestamptz not null default now()
+);
+
+-- Refresh tokens are stored hashed. Every refresh rotates the token; all
+-- tokens descending from the same login share a family_id so that reuse of
+-- a rotated token revokes the whole family.
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    id SERIAL primary key,
+    user_id integer not null references users(id) on delete cascade,
+    token_hash text not null unique,
+    family_id text not null,
+    expires_at timestamptz not null,
+    revoked_at timestamptz,
+    created_at timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens (family_id);
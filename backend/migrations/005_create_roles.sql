@@ -0,0 +1,26 @@
+-- Roles of users and the permissions each role grants. Permissions are
+-- enforced per route; edit role_permissions to change the policy, the API
+-- picks up changes without a restart.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(32) not null default 'viewer';
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role varchar(32) not null,
+    permission varchar(64) not null,
+    primary key (role, permission)
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('viewer', 'products:read'),
+    ('editor', 'products:read'),
+    ('editor', 'products:create'),
+    ('editor', 'products:update'),
+    ('editor', 'images:upload'),
+    ('admin', 'products:read'),
+    ('admin', 'products:create'),
+    ('admin', 'products:update'),
+    ('admin', 'products:delete'),
+    ('admin', 'products:import'),
+    ('admin', 'images:upload'),
+    ('admin', 'users:manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+-- API keys for machine-to-machine clients. Only a SHA-256 of the key is
+-- stored; the prefix identifies a key in listings without revealing it.
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id SERIAL primary key,
+    name varchar(255) not null,
+    prefix varchar(16) not null,
+    key_hash text not null unique,
+    scopes text[] not null default '{}',
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    created_by varchar(255),
+    created_at timestamptz not null default now()
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'apikeys:manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,23 @@
+-- Every product create, update and delete, written in the same transaction
+-- as the change itself. product_id is not a foreign key so that the history
+-- of deleted products is kept.
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id BIGSERIAL primary key,
+    product_id integer not null,
+    action varchar(16) not null,
+    actor varchar(255) not null,
+    request_id varchar(128) not null default '',
+    before jsonb,
+    after jsonb,
+    diff jsonb not null default '{}',
+    created_at timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_product_idx ON audit_log (product_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor, created_at);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('editor', 'audit:read'),
+    ('admin', 'audit:read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,18 @@
+-- The previous version of a product, written by every update so that a
+-- change can be rolled back. Revisions go away with their product; the
+-- audit log keeps the history of deleted products.
+CREATE TABLE IF NOT EXISTS product_revisions
+(
+    id BIGSERIAL primary key,
+    product_id integer not null REFERENCES products (id) ON DELETE CASCADE,
+    revision integer not null,
+    name varchar(255) not null,
+    type varchar(255) not null,
+    price numeric(10,2) not null,
+    description text,
+    image_url text,
+    private boolean not null default false,
+    created_by varchar(255) not null,
+    created_at timestamptz not null default now(),
+    UNIQUE (product_id, revision)
+);
@@ -0,0 +1,9 @@
+-- Bytes of images uploaded per principal and UTC day, for the daily upload
+-- quota. Rows of past days are only kept for reporting.
+CREATE TABLE IF NOT EXISTS upload_usage
+(
+    subject varchar(255) not null,
+    day date not null,
+    bytes bigint not null default 0,
+    primary key (subject, day)
+);
@@ -0,0 +1,28 @@
+-- Background jobs. Workers claim pending jobs with FOR UPDATE SKIP LOCKED,
+-- failed jobs go back to pending with a later run_at until max_attempts is
+-- reached and they are parked as dead for an operator to inspect or retry.
+CREATE TABLE IF NOT EXISTS jobs
+(
+    id BIGSERIAL primary key,
+    type varchar(64) not null,
+    payload jsonb not null default '{}',
+    status varchar(16) not null default 'pending',
+    attempts integer not null default 0,
+    max_attempts integer not null default 5,
+    -- At most one pending or running job per unique key
+    unique_key varchar(255),
+    run_at timestamptz not null default now(),
+    locked_by varchar(255),
+    locked_at timestamptz,
+    last_error text,
+    created_at timestamptz not null default now(),
+    finished_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS jobs_pending_idx ON jobs (run_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS jobs_status_idx ON jobs (status, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_unique_key_idx ON jobs (unique_key) WHERE status IN ('pending', 'running');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'jobs:manage')
+ON CONFLICT DO NOTHING;
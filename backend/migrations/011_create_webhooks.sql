@@ -0,0 +1,38 @@
+-- Outbound webhooks. Every product event matching a subscription gets a
+-- delivery row, sent by the job workers and kept as the delivery log.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id SERIAL primary key,
+    url text not null,
+    -- Signs the payloads, the receiver verifies them with the same secret
+    secret varchar(255) not null,
+    event_types text[] not null,
+    active boolean not null default true,
+    created_by varchar(255) not null default '',
+    created_at timestamptz not null default now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id BIGSERIAL primary key,
+    subscription_id integer not null references webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id varchar(64) not null,
+    event_type varchar(64) not null,
+    payload jsonb not null,
+    status varchar(16) not null default 'pending',
+    attempts integer not null default 0,
+    response_status integer,
+    response_body text,
+    last_error text,
+    duration_ms integer,
+    created_at timestamptz not null default now(),
+    delivered_at timestamptz,
+    -- An event is delivered once per subscription
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, created_at);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'webhooks:manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+-- Emails are unique regardless of case. Existing addresses are stored
+-- lowercase where that does not collide with another account; accounts
+-- differing only in case have to be merged by hand before this applies.
+UPDATE users u
+SET email = lower(u.email)
+WHERE u.email <> lower(u.email)
+  AND NOT EXISTS (SELECT 1 FROM users o WHERE o.id <> u.id AND lower(o.email) = lower(u.email));
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(email));
@@ -0,0 +1,5 @@
+-- Private products are only listed to roles that may see drafts.
+INSERT INTO role_permissions (role, permission) VALUES
+    ('editor', 'products:read_private'),
+    ('admin', 'products:read_private')
+ON CONFLICT DO NOTHING;
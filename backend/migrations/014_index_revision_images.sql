@@ -0,0 +1,3 @@
+-- Serving an image checks whether any revision of a private product still
+-- references it.
+CREATE INDEX IF NOT EXISTS product_revisions_image_url_idx ON product_revisions (image_url);
@@ -0,0 +1,152 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// migrationLockID keys the advisory lock held while migrating, the same on
+// every instance.
+const migrationLockID = 727_001
+
+// Run applies every embedded migration that has not been recorded in
+// schema_migrations yet, in filename order, each in its own transaction.
+// Instances starting together take turns through an advisory lock, so each
+// migration runs once.
+func Run(db *sql.DB, logger *slog.Logger) error {
+	ctx := context.Background()
+
+	// Session advisory locks belong to a connection, everything below runs
+	// on this one
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("error locking migrations: %v", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version text primary key,
+		applied_at timestamptz not null default now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %v", err)
+	}
+
+	// Read after taking the lock, another instance may just have applied
+	// some of them
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	versions, err := Versions()
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		if applied[version] {
+			continue
+		}
+
+		script, err := files.ReadFile(version + ".sql")
+		if err != nil {
+			return err
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(string(script)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error applying migration %s: %v", version, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error recording migration %s: %v", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		logger.Info("applied migration", "version", version)
+	}
+
+	return nil
+}
+
+// Pending returns the embedded migrations the database has not applied
+// yet. A server whose schema is behind its binary should not take traffic.
+func Pending(ctx context.Context, db *sql.DB) ([]string, error) {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := Versions()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []string{}
+	for _, version := range versions {
+		if !applied[version] {
+			pending = append(pending, version)
+		}
+	}
+	return pending, nil
+}
+
+// Versions lists the embedded migrations in the order they are applied.
+func Versions() ([]string, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(names))
+	for _, name := range names {
+		versions = append(versions, strings.TrimSuffix(name, ".sql"))
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Conn.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func appliedVersions(ctx context.Context, db queryer) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
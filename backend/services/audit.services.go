@@ -0,0 +1,28 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"context"
+)
+
+type AuditService struct {
+	repo models.AuditRepository
+}
+
+func NewAuditService(repo models.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+func (s *AuditService) List(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	return s.repo.List(ctx, filter)
+}
+
+func (s *AuditService) ProductHistory(ctx context.Context, productID int, page int, pageSize int) (*models.AuditPage, error) {
+	return s.List(ctx, models.AuditFilter{ProductID: productID, Page: page, PageSize: pageSize})
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+)
+
+const minPasswordLength = 8
+
+// dummyPasswordHash is compared against when the email is unknown, so that
+// a failed login takes as long whether or not the account exists.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// normalizeEmail is the form emails are stored and looked up in, they are
+// unique regardless of case.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// AuthService registers users, checks their passwords and issues
+// short-lived access tokens together with rotating refresh tokens.
+type AuthService struct {
+	users           models.UserRepository
+	tokenOptions    utils.AccessTokenOptions
+	refreshTokenTTL time.Duration
+	logger          *slog.Logger
+}
+
+func NewAuthService(users models.UserRepository, tokenOptions utils.AccessTokenOptions, refreshTokenTTL time.Duration, logger *slog.Logger) *AuthService {
+	return &AuthService{users: users, tokenOptions: tokenOptions, refreshTokenTTL: refreshTokenTTL, logger: logger}
+}
+
+func (s *AuthService) Register(email string, password string) (*models.User, error) {
+	email = normalizeEmail(email)
+	if !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("a valid email is required")
+	}
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing password: %v", err)
+	}
+
+	user := &models.User{Email: email, PasswordHash: string(hash)}
+	if err := s.users.Create(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *AuthService) Login(email string, password string) (*models.TokenPair, error) {
+	user, err := s.users.GetByEmail(normalizeEmail(email))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, familyID)
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented
+// token is revoked; presenting an already revoked token is treated as theft
+// and revokes every token of its family, logging the user out everywhere
+// that login was used.
+func (s *AuthService) Refresh(refreshToken string) (*models.TokenPair, error) {
+	token, err := s.users.GetRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if token.RevokedAt != nil {
+		s.logger.Warn("reuse of revoked refresh token detected, revoking token family", "user_id", token.UserID)
+		if err := s.users.RevokeRefreshTokenFamily(token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.users.RevokeRefreshToken(token.ID); err != nil {
+		if strings.Contains(err.Error(), "already revoked") {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, token.FamilyID)
+}
+
+// Logout revokes the refresh token and everything rotated from it.
+func (s *AuthService) Logout(refreshToken string) error {
+	token, err := s.users.GetRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+
+	return s.users.RevokeRefreshTokenFamily(token.FamilyID)
+}
+
+func (s *AuthService) issueTokens(user *models.User, familyID string) (*models.TokenPair, error) {
+	if s.tokenOptions.Secret == "" {
+		return nil, fmt.Errorf("AUTH_JWT_SECRET is not configured, cannot issue tokens")
+	}
+
+	accessToken, _, err := utils.IssueAccessToken(s.tokenOptions, strconv.Itoa(user.ID), user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("error signing access token: %v", err)
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.users.CreateRefreshToken(&models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.tokenOptions.TTL.Seconds()),
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used for refresh tokens, which are random and long enough
+// that a fast hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *AuthService) SetRole(userID int, role string) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+	return s.users.UpdateRole(userID, role)
+}
+
+func (s *AuthService) GetUserByEmail(email string) (*models.User, error) {
+	return s.users.GetByEmail(normalizeEmail(email))
+}
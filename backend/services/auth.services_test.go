@@ -0,0 +1,277 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/utils"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memoryUserRepository mirrors the error messages of the Postgres
+// repository, AuthService matches on them.
+type memoryUserRepository struct {
+	mu     sync.Mutex
+	users  []*models.User
+	tokens []*models.RefreshToken
+}
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return fmt.Errorf("email already registered")
+		}
+	}
+	user.ID = len(r.users) + 1
+	user.Role = models.RoleViewer
+	copied := *user
+	r.users = append(r.users, &copied)
+	return nil
+}
+
+func (r *memoryUserRepository) GetByID(id int) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.ID == id {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("user with ID %d not found", id)
+}
+
+func (r *memoryUserRepository) GetByEmail(email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("user with email %s not found", email)
+}
+
+func (r *memoryUserRepository) UpdateRole(id int, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.ID == id {
+			user.Role = role
+			return nil
+		}
+	}
+	return fmt.Errorf("user with ID %d not found", id)
+}
+
+func (r *memoryUserRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = len(r.tokens) + 1
+	copied := *token
+	r.tokens = append(r.tokens, &copied)
+	return nil
+}
+
+func (r *memoryUserRepository) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("refresh token not found")
+}
+
+func (r *memoryUserRepository) RevokeRefreshToken(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.ID == id {
+			if token.RevokedAt != nil {
+				return fmt.Errorf("refresh token already revoked")
+			}
+			now := time.Now()
+			token.RevokedAt = &now
+			return nil
+		}
+	}
+	return fmt.Errorf("refresh token not found")
+}
+
+func (r *memoryUserRepository) RevokeRefreshTokenFamily(familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+var testTokenOptions = utils.AccessTokenOptions{
+	Secret:   "test-secret",
+	Issuer:   "product-list",
+	Audience: "product-list-api",
+	TTL:      15 * time.Minute,
+}
+
+func newTestAuthService(t *testing.T) *AuthService {
+	t.Helper()
+	service := NewAuthService(&memoryUserRepository{}, testTokenOptions, time.Hour, discardLogger())
+	if _, err := service.Register("Alice@Example.com", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestLoginIssuesAccessToken(t *testing.T) {
+	service := newTestAuthService(t)
+
+	// Emails are unique regardless of case
+	pair, err := service.Login(" alice@example.COM", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := &utils.AccessClaims{}
+	_, err = jwt.ParseWithClaims(pair.AccessToken, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(testTokenOptions.Secret), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithIssuer(testTokenOptions.Issuer), jwt.WithAudience(testTokenOptions.Audience), jwt.WithExpirationRequired())
+	if err != nil {
+		t.Fatalf("access token does not validate: %v", err)
+	}
+	if claims.Subject != "1" || claims.Role != models.RoleViewer {
+		t.Errorf("claims = subject %q role %q, want user 1 as viewer", claims.Subject, claims.Role)
+	}
+	if pair.ExpiresIn != int(testTokenOptions.TTL.Seconds()) {
+		t.Errorf("expiresIn = %d, want %d", pair.ExpiresIn, int(testTokenOptions.TTL.Seconds()))
+	}
+}
+
+func TestLoginRejectsInvalidCredentials(t *testing.T) {
+	service := newTestAuthService(t)
+
+	if _, err := service.Login("alice@example.com", "wrong password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := service.Login("bob@example.com", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("unknown email: err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestRegisterRejectsDuplicateEmailInAnotherCase(t *testing.T) {
+	service := newTestAuthService(t)
+
+	if _, err := service.Register("ALICE@example.com", "another password"); err == nil {
+		t.Fatal("registered the same email twice")
+	}
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	service := newTestAuthService(t)
+
+	first, err := service.Login("alice@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := service.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh returned the same refresh token")
+	}
+
+	// The rotated token is single use
+	if _, err := service.Refresh(first.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reusing a rotated token: err = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// Reuse is treated as theft, the whole family is revoked
+	if _, err := service.Refresh(second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("token of a revoked family: err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshKeepsOtherLoginsValid(t *testing.T) {
+	service := newTestAuthService(t)
+
+	phone, err := service.Login("alice@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptop, err := service.Login("alice@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Logout(phone.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Refresh(phone.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after logout: err = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	pair, err := service.Refresh(laptop.RefreshToken)
+	if err != nil {
+		t.Fatalf("logging out one login revoked another: %v", err)
+	}
+	if pair.AccessToken == "" {
+		t.Error("no access token issued")
+	}
+}
+
+func TestRefreshRejectsExpiredTokens(t *testing.T) {
+	service := NewAuthService(&memoryUserRepository{}, testTokenOptions, -time.Minute, discardLogger())
+	if _, err := service.Register("alice@example.com", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	pair, err := service.Login("alice@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expired token: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := service.Refresh("not-a-token"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token: err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestSetRoleIsCarriedByNewTokens(t *testing.T) {
+	service := newTestAuthService(t)
+
+	if err := service.SetRole(1, models.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetRole(1, "superuser"); err == nil {
+		t.Error("accepted an unknown role")
+	}
+
+	pair, err := service.Login("alice@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := &utils.AccessClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(pair.AccessToken, claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != models.RoleEditor || claims.Subject != strconv.Itoa(1) {
+		t.Errorf("claims = subject %q role %q, want user 1 as editor", claims.Subject, claims.Role)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/migrations"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HealthService runs the readiness checks used by container orchestrators:
+// the database answers, the uploads directory is writable and the schema
+// is up to date with the migrations embedded in the binary.
+type HealthService struct {
+	db        *sql.DB
+	uploadDir string
+	timeout   time.Duration
+	logger    *slog.Logger
+}
+
+func NewHealthService(db *sql.DB, uploadDir string, timeout time.Duration, logger *slog.Logger) *HealthService {
+	return &HealthService{db: db, uploadDir: uploadDir, timeout: timeout, logger: logger}
+}
+
+// Ready runs every check concurrently, each bounded by the configured
+// timeout, and reports them in a fixed order.
+func (s *HealthService) Ready(ctx context.Context) *models.HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	checks := []struct {
+		name string
+		run  func(context.Context) error
+	}{
+		{"database", s.checkDatabase},
+		{"uploads", s.checkUploads},
+		{"migrations", s.checkMigrations},
+	}
+
+	report := &models.HealthReport{
+		Status: models.HealthStatusOK,
+		Checks: make([]models.HealthCheck, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check.run(ctx)
+			result := models.HealthCheck{Name: check.name, Status: models.HealthStatusOK}
+			if err != nil {
+				result.Status = models.HealthStatusUnavailable
+				s.logger.WarnContext(ctx, "readiness check failed", "check", check.name, "duration_ms", time.Since(start).Milliseconds(), "error", err)
+			}
+			report.Checks[i] = result
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status != models.HealthStatusOK {
+			report.Status = models.HealthStatusUnavailable
+		}
+	}
+	return report
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// checkUploads creates and removes a file, a read-only or full volume
+// fails here rather than on the next image upload.
+func (s *HealthService) checkUploads(ctx context.Context) error {
+	f, err := os.CreateTemp(s.uploadDir, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+func (s *HealthService) checkMigrations(ctx context.Context) error {
+	pending, err := migrations.Pending(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// ImageGCService removes image files from the uploads directory that are no
+// longer referenced by any product (replaced on update, left behind by a
+// delete, or written by an upload whose insert failed).
+type ImageGCService struct {
+	repo        models.ProductRepository
+	images      models.ImageRepository
+	uploadDir   string
+	gracePeriod time.Duration
+	logger      *slog.Logger
+}
+
+func NewImageGCService(repo models.ProductRepository, images models.ImageRepository, uploadDir string, gracePeriod time.Duration, logger *slog.Logger) *ImageGCService {
+	return &ImageGCService{repo: repo, images: images, uploadDir: uploadDir, gracePeriod: gracePeriod, logger: logger}
+}
+
+// Run reconciles the files on disk against the image_url column.
+// Orphans younger than the grace period are reported but kept, so that an
+// upload whose product row has not been inserted yet is never removed.
+// With dryRun set, nothing is deleted.
+func (s *ImageGCService) Run(ctx context.Context, dryRun bool) (*models.GCReport, error) {
+	report := &models.GCReport{
+		StartedAt:   time.Now(),
+		DryRun:      dryRun,
+		GracePeriod: s.gracePeriod.String(),
+		Orphans:     []models.OrphanedImage{},
+	}
+
+	urls, err := s.repo.GetImageURLs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading image references: %v", err)
+	}
+
+	// image_url is stored as "/uploads/<filename>", compare on the filename
+	referenced := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		referenced[path.Base(url)] = true
+	}
+
+	entries, err := os.ReadDir(s.uploadDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		report.Scanned++
+
+		if referenced[entry.Name()] {
+			report.Referenced++
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			s.logger.ErrorContext(ctx, "reading file info failed", "file", entry.Name(), "error", err)
+			continue
+		}
+
+		orphan := models.OrphanedImage{
+			Filename:   entry.Name(),
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime(),
+		}
+
+		if !dryRun && report.StartedAt.Sub(info.ModTime()) >= s.gracePeriod && s.collectable(ctx, entry.Name()) {
+			if err := os.Remove(filepath.Join(s.uploadDir, entry.Name())); err != nil {
+				s.logger.ErrorContext(ctx, "deleting orphaned image failed", "file", entry.Name(), "error", err)
+			} else {
+				orphan.Deleted = true
+				report.Deleted++
+				report.FreedBytes += info.Size()
+			}
+		}
+
+		report.Orphans = append(report.Orphans, orphan)
+	}
+
+	// Drop reference count rows of images no product uses anymore
+	if !dryRun {
+		if _, err := s.images.PruneUnreferenced(); err != nil {
+			s.logger.ErrorContext(ctx, "pruning image reference counts failed", "error", err)
+		}
+	}
+
+	return report, nil
+}
+
+// collectable checks an orphan again right before it is removed: the
+// reference counts and revisions may have picked it up since the scan
+// started, and a new upload of the same content touches the file.
+func (s *ImageGCService) collectable(ctx context.Context, filename string) bool {
+	referenced, err := s.images.IsReferenced(ctx, "/uploads/"+filename)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "checking image references failed, keeping it", "file", filename, "error", err)
+		return false
+	}
+	if referenced {
+		return false
+	}
+
+	info, err := os.Stat(filepath.Join(s.uploadDir, filename))
+	return err == nil && time.Since(info.ModTime()) >= s.gracePeriod
+}
+
+// RunJob is the handler of models.JobTypeImageGC jobs, queued every
+// IMAGE_GC_INTERVAL so that a single instance collects at a time.
+func (s *ImageGCService) RunJob(ctx context.Context, payload json.RawMessage) error {
+	var params models.GCJobPayload
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return fmt.Errorf("invalid image GC payload: %v", err)
+	}
+
+	report, err := s.Run(ctx, params.DryRun)
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "image GC finished", "scanned", report.Scanned, "orphaned", len(report.Orphans),
+		"deleted", report.Deleted, "freed_bytes", report.FreedBytes, "dry_run", report.DryRun)
+	return nil
+}
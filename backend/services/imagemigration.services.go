@@ -0,0 +1,109 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/utils"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// legacyImageName matches files written before images were content
+// addressed, e.g. product-1731655410939172200.jpg
+var legacyImageName = regexp.MustCompile(`^product-\d+\.[A-Za-z0-9]+$`)
+
+// ImageMigrationService renames legacy uploads to their content address,
+// points the products at the new files and rebuilds the reference counts.
+type ImageMigrationService struct {
+	images    models.ImageRepository
+	uploadDir string
+	logger    *slog.Logger
+}
+
+func NewImageMigrationService(images models.ImageRepository, uploadDir string, logger *slog.Logger) *ImageMigrationService {
+	return &ImageMigrationService{images: images, uploadDir: uploadDir, logger: logger}
+}
+
+func (s *ImageMigrationService) Run(dryRun bool) (*models.ImageMigrationReport, error) {
+	report := &models.ImageMigrationReport{
+		DryRun:   dryRun,
+		Migrated: []models.MigratedImage{},
+		Failed:   []string{},
+	}
+
+	entries, err := os.ReadDir(s.uploadDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !legacyImageName.MatchString(entry.Name()) {
+			continue
+		}
+
+		migrated, err := s.migrate(entry.Name(), dryRun)
+		if err != nil {
+			s.logger.Error("migrating image failed", "file", entry.Name(), "error", err)
+			report.Failed = append(report.Failed, entry.Name())
+			continue
+		}
+		report.Migrated = append(report.Migrated, *migrated)
+	}
+
+	if !dryRun {
+		if err := s.images.RebuildRefCounts(); err != nil {
+			return report, fmt.Errorf("error rebuilding reference counts: %v", err)
+		}
+	}
+
+	return report, nil
+}
+
+func (s *ImageMigrationService) migrate(name string, dryRun bool) (*models.MigratedImage, error) {
+	source := filepath.Join(s.uploadDir, name)
+	ext := filepath.Ext(name)
+
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	target, err := utils.ContentAddressedName(f, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	migrated := &models.MigratedImage{
+		From: "/uploads/" + name,
+		To:   "/uploads/" + target,
+	}
+	if _, err := os.Stat(filepath.Join(s.uploadDir, target)); err == nil {
+		migrated.Deduped = true
+	}
+	if dryRun {
+		return migrated, nil
+	}
+
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	if _, err := utils.SaveImage(context.Background(), f, ext); err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	// The old file is only removed once no product points at it anymore
+	migrated.Products, err = s.images.ReplaceImageURL(migrated.From, migrated.To)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(source); err != nil {
+		s.logger.Error("removing migrated image failed", "file", name, "error", err)
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"PRODUCT_LIST/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrImageNotFound     = errors.New("image not found")
+	ErrPresetNotAllowed  = errors.New("requested size is not an allowed preset")
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageDimensions   = errors.New("image dimensions exceed the allowed maximum")
+)
+
+// DefaultImagePresets is the allowlist of renditions. Arbitrary sizes are
+// rejected so that clients cannot fill the cache or burn CPU with endless
+// variations of the same image.
+var DefaultImagePresets = []models.ImagePreset{
+	{Name: "thumb", Width: 150, Height: 150, Fit: "cover"},
+	{Name: "card", Width: 400, Height: 300, Fit: "cover"},
+	{Name: "small", Width: 320, Height: 320, Fit: "contain"},
+	{Name: "medium", Width: 640, Height: 640, Fit: "contain"},
+	{Name: "large", Width: 1280, Height: 1280, Fit: "contain"},
+}
+
+// ImageTransformService resizes, crops and transcodes stored product images
+// and keeps the generated renditions in a disk LRU cache.
+type ImageTransformService struct {
+	sourceDir string
+	cache     *utils.DiskCache
+	presets   []models.ImagePreset
+	// maxPixels bounds width*height of the images decoded, a small file can
+	// declare dimensions that take gigabytes to decode
+	maxPixels int64
+	renders   singleflight.Group
+}
+
+func NewImageTransformService(sourceDir string, cache *utils.DiskCache, presets []models.ImagePreset, maxPixels int64) *ImageTransformService {
+	return &ImageTransformService{sourceDir: sourceDir, cache: cache, presets: presets, maxPixels: maxPixels}
+}
+
+func (s *ImageTransformService) Presets() []models.ImagePreset {
+	return s.presets
+}
+
+// ResolvePreset finds the preset matching either its name or the requested
+// width, height and fit. It returns nil when no transformation was asked for.
+func (s *ImageTransformService) ResolvePreset(name string, width int, height int, fit string) (*models.ImagePreset, error) {
+	if name == "" && width == 0 && height == 0 && fit == "" {
+		return nil, nil
+	}
+
+	for i := range s.presets {
+		preset := &s.presets[i]
+		if name != "" {
+			if preset.Name == name {
+				return preset, nil
+			}
+			continue
+		}
+		if preset.Width == width && preset.Height == height && (fit == "" || preset.Fit == fit) {
+			return preset, nil
+		}
+	}
+
+	return nil, ErrPresetNotAllowed
+}
+
+// Render opens the rendition of key for the given preset and output format
+// ("jpeg" or "png", empty keeps the source format) and returns it with its
+// content type, the caller closes the file. A nil preset only transcodes, in
+// the source format it returns the stored file itself.
+func (s *ImageTransformService) Render(key string, preset *models.ImagePreset, format string) (*os.File, string, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, "/\\") {
+		return nil, "", ErrImageNotFound
+	}
+
+	sourcePath := filepath.Join(s.sourceDir, key)
+	if info, err := os.Stat(sourcePath); err != nil || info.IsDir() {
+		return nil, "", ErrImageNotFound
+	}
+
+	format, err := outputFormat(key, format)
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := "image/" + format
+
+	// The original in its own format is served as stored, without decoding
+	// it or keeping a copy in the cache
+	if preset == nil {
+		if sourceFormat, err := outputFormat(key, ""); err == nil && sourceFormat == format {
+			file, err := os.Open(sourcePath)
+			if err != nil {
+				return nil, "", ErrImageNotFound
+			}
+			return file, contentType, nil
+		}
+	}
+
+	presetName := "original"
+	if preset != nil {
+		presetName = preset.Name
+	}
+	cacheKey := fmt.Sprintf("%s_%s.%s", key, presetName, format)
+
+	// Other renditions may evict this one between rendering and opening it,
+	// it is rendered again then
+	for attempt := 0; attempt < 3; attempt++ {
+		if file, ok := s.cache.Get(cacheKey); ok {
+			return file, contentType, nil
+		}
+
+		// Concurrent requests for the same missing rendition render it once
+		_, err, _ := s.renders.Do(cacheKey, func() (interface{}, error) {
+			return nil, s.render(sourcePath, cacheKey, preset, presetName, format)
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return nil, "", fmt.Errorf("error caching image: %s evicted before it was served", cacheKey)
+}
+
+func (s *ImageTransformService) render(sourcePath string, cacheKey string, preset *models.ImagePreset, presetName string, format string) error {
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		return ErrImageNotFound
+	}
+	defer f.Close()
+
+	start := time.Now()
+
+	// Check the declared dimensions before allocating anything for them
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return fmt.Errorf("error decoding image: %v", err)
+	}
+	if int64(config.Width)*int64(config.Height) > s.maxPixels {
+		return ErrImageDimensions
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error decoding image: %v", err)
+	}
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("error decoding image: %v", err)
+	}
+
+	var dst image.Image = src
+	if preset != nil {
+		dst = resizeImage(src, *preset)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, flatten(dst), &jpeg.Options{Quality: 85})
+	case "png":
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return fmt.Errorf("error encoding image: %v", err)
+	}
+	metrics.ImageProcessingDuration.WithLabelValues(presetName).Observe(time.Since(start).Seconds())
+
+	if err := s.cache.Put(cacheKey, buf.Bytes()); err != nil {
+		return fmt.Errorf("error caching image: %v", err)
+	}
+	return nil
+}
+
+// RenderJob is the handler of models.JobTypeImageRenditions jobs: it renders
+// every preset of a newly stored image, so the first visitors do not wait
+// for the resizing.
+func (s *ImageTransformService) RenderJob(ctx context.Context, payload json.RawMessage) error {
+	var params models.ImageJobPayload
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return fmt.Errorf("invalid image job payload: %v", err)
+	}
+
+	key := filepath.Base(params.ImageURL)
+	for i := range s.presets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		file, _, err := s.Render(key, &s.presets[i], "")
+		if err == nil {
+			file.Close()
+		}
+		if errors.Is(err, ErrImageNotFound) || errors.Is(err, ErrImageDimensions) {
+			// Removed since, or never renderable, retrying does not help
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func outputFormat(key string, requested string) (string, error) {
+	if requested == "" {
+		requested = strings.TrimPrefix(strings.ToLower(filepath.Ext(key)), ".")
+	}
+
+	switch requested {
+	case "jpg", "jpeg":
+		return "jpeg", nil
+	case "png":
+		return "png", nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+func resizeImage(src image.Image, preset models.ImagePreset) image.Image {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := float64(bounds.Dx()), float64(bounds.Dy())
+	width, height := float64(preset.Width), float64(preset.Height)
+
+	switch preset.Fit {
+	case "fill":
+		return scaleImage(src, bounds, preset.Width, preset.Height)
+	case "contain":
+		// Never upscale, only shrink to fit inside the box
+		ratio := min(width/srcWidth, height/srcHeight, 1)
+		return scaleImage(src, bounds, max(1, int(srcWidth*ratio)), max(1, int(srcHeight*ratio)))
+	default: // cover
+		// Crop the centered region with the target aspect ratio, then scale it
+		ratio := max(width/srcWidth, height/srcHeight)
+		cropWidth, cropHeight := int(width/ratio), int(height/ratio)
+		x0 := bounds.Min.X + (bounds.Dx()-cropWidth)/2
+		y0 := bounds.Min.Y + (bounds.Dy()-cropHeight)/2
+		return scaleImage(src, image.Rect(x0, y0, x0+cropWidth, y0+cropHeight), preset.Width, preset.Height)
+	}
+}
+
+func scaleImage(src image.Image, srcRect image.Rectangle, width int, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Over, nil)
+	return dst
+}
+
+// flatten draws img over a white background, JPEG has no transparency.
+func flatten(img image.Image) image.Image {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"PRODUCT_LIST/tracing"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// JobHandler runs one job. A returned error schedules a retry, until the
+// job runs out of attempts and is moved to the dead jobs.
+type JobHandler func(ctx context.Context, payload json.RawMessage) error
+
+type JobOptions struct {
+	Concurrency  int
+	PollInterval time.Duration
+	// Timeout bounds a single run, a job still locked after Timeout plus a
+	// grace minute is considered abandoned and requeued
+	Timeout     time.Duration
+	MaxAttempts int
+	// Retries back off exponentially from RetryBackoff up to MaxBackoff
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+}
+
+// JobService queues background work in Postgres and runs it on a pool of
+// workers, so that nothing slow runs on request goroutines.
+type JobService struct {
+	repo     models.JobRepository
+	options  JobOptions
+	logger   *slog.Logger
+	workerID string
+	handlers map[string]JobHandler
+}
+
+func NewJobService(repo models.JobRepository, options JobOptions, logger *slog.Logger) *JobService {
+	host, _ := os.Hostname()
+	return &JobService{
+		repo:     repo,
+		options:  options,
+		logger:   logger,
+		workerID: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		handlers: make(map[string]JobHandler),
+	}
+}
+
+// Register sets the handler of a job type. Handlers are registered before
+// Start, workers only claim job types they have a handler for.
+func (s *JobService) Register(jobType string, handler JobHandler) {
+	s.handlers[jobType] = handler
+}
+
+// Enqueue queues a job of the given type. Called with the context of a unit
+// of work, the job is queued in its transaction.
+func (s *JobService) Enqueue(ctx context.Context, jobType string, payload interface{}) (*models.Job, error) {
+	job, _, err := s.enqueue(ctx, jobType, "", payload)
+	return job, err
+}
+
+// EnqueueUnique queues a job unless one with the same key is already
+// pending or running, queued reports which happened.
+func (s *JobService) EnqueueUnique(ctx context.Context, jobType string, uniqueKey string, payload interface{}) (queued bool, err error) {
+	_, queued, err = s.enqueue(ctx, jobType, uniqueKey, payload)
+	return queued, err
+}
+
+func (s *JobService) enqueue(ctx context.Context, jobType string, uniqueKey string, payload interface{}) (*models.Job, bool, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, false, fmt.Errorf("error encoding job payload: %v", err)
+	}
+
+	job := &models.Job{
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: s.options.MaxAttempts,
+		UniqueKey:   uniqueKey,
+		RunAt:       time.Now(),
+	}
+	queued, err := s.repo.Enqueue(ctx, job)
+	if err != nil {
+		return nil, false, err
+	}
+	return job, queued, nil
+}
+
+// Schedule queues a job of the given type every interval until the returned
+// stop function is called. The job type is its unique key, with several
+// instances running only one of them gets queued. An interval of zero or
+// less disables the schedule.
+func (s *JobService) Schedule(jobType string, interval time.Duration, payload interface{}) (stop func()) {
+	if interval <= 0 {
+		s.logger.Info("job schedule disabled", "job_type", jobType)
+		return func() {}
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.EnqueueUnique(context.Background(), jobType, jobType, payload); err != nil {
+					s.logger.Error("scheduling job failed", "job_type", jobType, "error", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// Start runs the workers and the requeueing of abandoned jobs until the
+// returned stop function is called. Stop waits for running jobs to finish.
+func (s *JobService) Start() (stop func()) {
+	types := make([]string, 0, len(s.handlers))
+	for jobType := range s.handlers {
+		types = append(types, jobType)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < s.options.Concurrency; i++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			s.work(worker, types, done)
+		}(fmt.Sprintf("%s/%d", s.workerID, i))
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.requeueStale(done)
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func (s *JobService) work(worker string, types []string, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		job, err := s.repo.Claim(context.Background(), types, worker)
+		if err != nil {
+			s.logger.Error("claiming job failed", "worker", worker, "error", err)
+		}
+		if job == nil {
+			// Nothing due (or the database is unavailable), poll again later
+			select {
+			case <-done:
+				return
+			case <-time.After(s.options.PollInterval):
+			}
+			continue
+		}
+
+		s.run(job)
+	}
+}
+
+// run executes a claimed job and records the outcome.
+func (s *JobService) run(job *models.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.Timeout)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "job "+job.Type,
+		attribute.Int64("job.id", job.ID),
+		attribute.Int("job.attempt", job.Attempts),
+	)
+	err := s.runHandler(ctx, job)
+	tracing.End(span, err)
+
+	// Recording the outcome must not fail because the job used up its time
+	ctx = context.WithoutCancel(ctx)
+
+	if err == nil {
+		if err := s.repo.Complete(ctx, job.ID, job.LockedBy); err != nil {
+			s.logger.ErrorContext(ctx, "completing job failed", "job_id", job.ID, "error", err)
+		}
+		metrics.JobsProcessed.WithLabelValues(job.Type, "succeeded").Inc()
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		s.logger.ErrorContext(ctx, "job failed, moved to dead jobs", "job_id", job.ID, "job_type", job.Type, "attempts", job.Attempts, "error", err)
+		if err := s.repo.Fail(ctx, job.ID, job.LockedBy, err.Error(), nil); err != nil {
+			s.logger.ErrorContext(ctx, "recording dead job failed", "job_id", job.ID, "error", err)
+		}
+		metrics.JobsProcessed.WithLabelValues(job.Type, "dead").Inc()
+		return
+	}
+
+	retryAt := time.Now().Add(s.backoff(job.Attempts))
+	s.logger.WarnContext(ctx, "job failed, retrying", "job_id", job.ID, "job_type", job.Type, "attempts", job.Attempts, "retry_at", retryAt, "error", err)
+	if err := s.repo.Fail(ctx, job.ID, job.LockedBy, err.Error(), &retryAt); err != nil {
+		s.logger.ErrorContext(ctx, "recording job retry failed", "job_id", job.ID, "error", err)
+	}
+	metrics.JobsProcessed.WithLabelValues(job.Type, "retried").Inc()
+}
+
+// runHandler turns a handler panic into a failed run, a bad job must not
+// take the worker down.
+func (s *JobService) runHandler(ctx context.Context, job *models.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	handler, ok := s.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler for job type %q", job.Type)
+	}
+	return handler(ctx, job.Payload)
+}
+
+// backoff doubles with every attempt, with up to 20% jitter so that jobs
+// failing together do not retry in lockstep.
+func (s *JobService) backoff(attempts int) time.Duration {
+	backoff := s.options.RetryBackoff
+	for i := 1; i < attempts && backoff < s.options.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.options.MaxBackoff {
+		backoff = s.options.MaxBackoff
+	}
+	return backoff + time.Duration(rand.Int63n(int64(backoff)/5+1))
+}
+
+func (s *JobService) requeueStale(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			requeued, err := s.repo.RequeueStale(context.Background(), time.Now().Add(-s.options.Timeout-time.Minute))
+			if err != nil {
+				s.logger.Error("requeueing abandoned jobs failed", "error", err)
+				continue
+			}
+			if requeued > 0 {
+				s.logger.Warn("requeued abandoned jobs", "jobs", requeued)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (s *JobService) List(ctx context.Context, filter models.JobFilter) (*models.JobPage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	return s.repo.List(ctx, filter)
+}
+
+func (s *JobService) Get(ctx context.Context, id int64) (*models.Job, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Retry sends a dead job back to the queue with fresh attempts.
+func (s *JobService) Retry(ctx context.Context, id int64) (*models.Job, error) {
+	job, err := s.repo.Retry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "dead job retried", "job_id", id, "job_type", job.Type)
+	return job, nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// PolicyService answers which role holds which permission. The policy is
+// read from the role_permissions table and reloaded in the background, so it
+// can be changed without redeploying.
+type PolicyService struct {
+	repo            models.PolicyRepository
+	refreshInterval time.Duration
+	logger          *slog.Logger
+
+	mu     sync.RWMutex
+	policy map[string]map[string]bool
+}
+
+func NewPolicyService(repo models.PolicyRepository, refreshInterval time.Duration, logger *slog.Logger) *PolicyService {
+	return &PolicyService{repo: repo, refreshInterval: refreshInterval, logger: logger}
+}
+
+// Allowed reports whether role grants permission.
+func (s *PolicyService) Allowed(role string, permission string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy[role][permission]
+}
+
+func (s *PolicyService) Reload() error {
+	rolePermissions, err := s.repo.GetRolePermissions()
+	if err != nil {
+		return err
+	}
+
+	policy := make(map[string]map[string]bool, len(rolePermissions))
+	for role, permissions := range rolePermissions {
+		policy[role] = make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			policy[role][permission] = true
+		}
+	}
+
+	s.mu.Lock()
+	s.policy = policy
+	s.mu.Unlock()
+	return nil
+}
+
+// StartRefresh reloads the policy every refresh interval until the returned
+// stop function is called, so requests never wait on the database for it.
+// A refresh interval of zero or less keeps the policy loaded at startup.
+func (s *PolicyService) StartRefresh() (stop func()) {
+	if s.refreshInterval <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(s.refreshInterval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				// Keep serving the previous policy if the reload fails
+				if err := s.Reload(); err != nil {
+					s.logger.Error("reloading role permissions failed", "error", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
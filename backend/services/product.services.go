@@ -2,22 +2,45 @@ package services
 
 import (
 	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/tracing"
+	"PRODUCT_LIST/utils"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
 )
 
+// MaxImportProducts is the most products one import may create.
+const MaxImportProducts = 1000
+
 type ProductService struct {
-	repo models.ProductRepository
+	repo     models.ProductRepository
+	images   models.ImageRepository
+	uow      models.UnitOfWork
+	jobs     *JobService
+	webhooks *WebhookService
+	logger   *slog.Logger
 }
 
-func NewProductService(repo models.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+func NewProductService(repo models.ProductRepository, images models.ImageRepository, uow models.UnitOfWork, jobs *JobService, webhooks *WebhookService, logger *slog.Logger) *ProductService {
+	return &ProductService{repo: repo, images: images, uow: uow, jobs: jobs, webhooks: webhooks, logger: logger}
 }
 
-func (s *ProductService) GetProducts(page int, pageSize int) ([]models.Product, error) {
-	return s.repo.GetAll(page, pageSize)
+func (s *ProductService) GetProducts(ctx context.Context, page int, pageSize int, includePrivate bool) ([]models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProducts")
+	defer span.End()
+
+	return s.repo.GetAll(ctx, page, pageSize, includePrivate)
 }
 
-func (s *ProductService) GetPagedProducts(params models.FilterParams) (*models.PaginatedResponse, error) {
+func (s *ProductService) GetPagedProducts(ctx context.Context, params models.FilterParams) (*models.PaginatedResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetPagedProducts")
+	defer span.End()
+
 	if params.Page < 1 {
 		params.Page = 1
 	}
@@ -33,27 +56,214 @@ func (s *ProductService) GetPagedProducts(params models.FilterParams) (*models.P
 		params.MaxPrice = params.MinPrice
 	}
 
-	return s.repo.GetProducts(params)
+	return s.repo.GetProducts(ctx, params)
 }
 
-func (s *ProductService) GetProductByID(id int) (*models.Product, error) {
-	return s.repo.GetByID(id)
+func (s *ProductService) GetProductByID(ctx context.Context, id int) (*models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProductByID")
+	defer span.End()
+
+	return s.repo.GetByID(ctx, id)
 }
 
-func (s *ProductService) Create(product *models.Product) error {
-	return s.repo.Create(product)
+func (s *ProductService) Create(ctx context.Context, product *models.Product) error {
+	return s.CreateWithImage(ctx, product, nil, "")
 }
 
-func (s *ProductService) GetProduct(id int) (*models.Product, error) {
-	return s.repo.GetByID(id)
+// CreateWithImage stores the image read from image, when there is one, and
+// creates the product in one unit of work. A failed insert removes the
+// stored file again.
+func (s *ProductService) CreateWithImage(ctx context.Context, product *models.Product, image io.Reader, ext string) error {
+	ctx, span := tracing.Start(ctx, "ProductService.Create")
+	defer span.End()
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.storeImage(ctx, product, image, ext); err != nil {
+			return err
+		}
+		if err := s.repo.Create(ctx, product); err != nil {
+			return err
+		}
+		return s.webhooks.Publish(ctx, models.EventProductCreated, eventProduct(product))
+	})
 }
 
-func (s *ProductService) DeleteProduct(id int) error {
-	return s.repo.Delete(id)
+func (s *ProductService) GetProduct(ctx context.Context, id int) (*models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProduct")
+	defer span.End()
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *ProductService) DeleteProduct(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "ProductService.DeleteProduct")
+	defer span.End()
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.webhooks.Publish(ctx, models.EventProductDeleted, map[string]int{"id": id})
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "product deleted", "product_id", id)
+	return nil
+}
+
+func (s *ProductService) UpdateProduct(ctx context.Context, product *models.Product) error {
+	return s.UpdateWithImage(ctx, product, nil, "")
+}
+
+// UpdateWithImage is UpdateProduct with a new image, stored and referenced
+// in one unit of work like CreateWithImage.
+func (s *ProductService) UpdateWithImage(ctx context.Context, product *models.Product, image io.Reader, ext string) error {
+	ctx, span := tracing.Start(ctx, "ProductService.UpdateProduct")
+	defer span.End()
+
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.storeImage(ctx, product, image, ext); err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, product); err != nil {
+			return err
+		}
+		return s.webhooks.Publish(ctx, models.EventProductUpdated, eventProduct(product))
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "product updated", "product_id", product.ID)
+	return nil
+}
+
+// ImportProducts creates the products in one unit of work, so one invalid
+// or failing product imports none of them. Images are not imported, they
+// are uploaded per product afterwards.
+func (s *ProductService) ImportProducts(ctx context.Context, products []models.Product) error {
+	ctx, span := tracing.Start(ctx, "ProductService.ImportProducts")
+	defer span.End()
+
+	if len(products) == 0 {
+		return fmt.Errorf("at least one product is required")
+	}
+	if len(products) > MaxImportProducts {
+		return fmt.Errorf("invalid import, at most %d products are allowed", MaxImportProducts)
+	}
+	for i := range products {
+		if err := validateProduct(&products[i]); err != nil {
+			return fmt.Errorf("invalid product %d: %v", i, err)
+		}
+	}
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		for i := range products {
+			product := &products[i]
+			product.Image, product.ImageURL = "", ""
+			if err := s.repo.Create(ctx, product); err != nil {
+				return err
+			}
+			if err := s.webhooks.Publish(ctx, models.EventProductCreated, eventProduct(product)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "products imported", "count", len(products))
+	return nil
+}
+
+func (s *ProductService) SearchProducts(ctx context.Context, name string, includePrivate bool) ([]models.Product, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SearchProducts")
+	defer span.End()
+
+	return s.repo.Search(ctx, name, includePrivate)
+}
+
+func (s *ProductService) IsPrivateImage(ctx context.Context, imageURL string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.IsPrivateImage")
+	defer span.End()
+
+	return s.repo.IsPrivateImage(ctx, imageURL)
+}
+
+// storeImage saves the new image of a product, read from image or from the
+// base64 payload of JSON requests, and points ImageURL at it. A file this
+// unit of work wrote is removed if it rolls back, unless another request
+// uses the same content meanwhile.
+func (s *ProductService) storeImage(ctx context.Context, product *models.Product, image io.Reader, ext string) error {
+	if image == nil && product.Image != "" {
+		data, err := utils.DecodeBase64Image(product.Image)
+		if err != nil {
+			return err
+		}
+		image, ext = bytes.NewReader(data), ".png"
+	}
+	if image == nil {
+		return nil
+	}
+
+	imageURL, created, err := utils.StoreImage(ctx, image, ext)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "saving image failed", "error", err)
+		return err
+	}
+	if created {
+		// StoreImage touches a file when it deduplicates content into it, a
+		// changed modification time means another request picked it up
+		if info, err := os.Stat(filepath.Join(utils.UploadDir, filepath.Base(imageURL))); err == nil {
+			modTime := info.ModTime()
+			models.OnRollback(ctx, func() { s.discardImage(ctx, imageURL, modTime) })
+		}
+
+		// Renditions are rendered in the background, queued with the change
+		if _, err := s.jobs.Enqueue(ctx, models.JobTypeImageRenditions, models.ImageJobPayload{ImageURL: imageURL}); err != nil {
+			return err
+		}
+	}
+
+	product.ImageURL = imageURL
+	return nil
+}
+
+// discardImage removes an image stored by a unit of work that rolled back.
+// A concurrent request may have stored the same content, it is kept when
+// that request touched the file or committed a reference to it. Anything
+// left over is collected by the image GC.
+func (s *ProductService) discardImage(ctx context.Context, imageURL string, modTime time.Time) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	info, err := os.Stat(filepath.Join(utils.UploadDir, filepath.Base(imageURL)))
+	if err != nil || !info.ModTime().Equal(modTime) {
+		return
+	}
+
+	referenced, err := s.images.IsReferenced(ctx, imageURL)
+	if err != nil {
+		// The image GC removes it later if it stays unreferenced
+		s.logger.WarnContext(ctx, "checking rolled back image failed", "image_url", imageURL, "error", err)
+		return
+	}
+	if referenced {
+		return
+	}
+
+	if err := utils.RemoveImage(imageURL); err != nil {
+		s.logger.WarnContext(ctx, "removing rolled back image failed", "image_url", imageURL, "error", err)
+		return
+	}
+	s.logger.InfoContext(ctx, "removed image of rolled back change", "image_url", imageURL)
 }
 
-func (s *ProductService) UpdateProduct(product *models.Product) error {
-	// Add validation
+func validateProduct(product *models.Product) error {
 	if product.Name == "" {
 		return fmt.Errorf("name cannot be empty")
 	}
@@ -63,9 +273,13 @@ func (s *ProductService) UpdateProduct(product *models.Product) error {
 	if product.Price <= 0 {
 		return fmt.Errorf("price must be greater than zero")
 	}
-	return s.repo.Update(product)
+	return nil
 }
 
-func (s *ProductService) SearchProducts(name string) ([]models.Product, error) {
-	return s.repo.Search(name)
+// eventProduct is the product as sent in webhook events, without the base64
+// upload payload.
+func eventProduct(product *models.Product) models.Product {
+	event := *product
+	event.Image = ""
+	return event
 }
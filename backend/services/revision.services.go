@@ -0,0 +1,64 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"context"
+)
+
+// RevisionService lists and compares earlier versions of a product and rolls
+// a product back to one of them.
+type RevisionService struct {
+	revisions models.RevisionRepository
+	products  *ProductService
+}
+
+func NewRevisionService(revisions models.RevisionRepository, products *ProductService) *RevisionService {
+	return &RevisionService{revisions: revisions, products: products}
+}
+
+func (s *RevisionService) List(ctx context.Context, productID int) ([]models.ProductRevision, error) {
+	if _, err := s.products.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.revisions.List(ctx, productID)
+}
+
+func (s *RevisionService) Get(ctx context.Context, productID int, revision int) (*models.ProductRevision, error) {
+	return s.revisions.Get(ctx, productID, revision)
+}
+
+func (s *RevisionService) Diff(ctx context.Context, productID int, from int, to int) (*models.RevisionDiff, error) {
+	fromRevision, err := s.revisions.Get(ctx, productID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := s.revisions.Get(ctx, productID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := models.DiffProducts(&fromRevision.Product, &toRevision.Product)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RevisionDiff{ProductID: productID, From: from, To: to, Changes: changes}, nil
+}
+
+// Restore puts the fields and image of a revision back on the product. This
+// goes through a regular update, so the version being replaced becomes a new
+// revision and the restore can itself be undone.
+func (s *RevisionService) Restore(ctx context.Context, productID int, revision int) (*models.Product, error) {
+	rev, err := s.revisions.Get(ctx, productID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	product := rev.Product
+	product.ID = productID
+	if err := s.products.UpdateProduct(ctx, &product); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
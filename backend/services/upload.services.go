@@ -0,0 +1,322 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match")
+	ErrUploadTooLarge       = errors.New("upload exceeds maximum size")
+	ErrUploadIncomplete     = errors.New("upload is not complete")
+)
+
+// UploadService implements resumable (tus-style) uploads: an upload is
+// created with its final length, receives chunks at increasing offsets and,
+// once complete, is attached to a product through the regular image pipeline.
+type UploadService struct {
+	dir      string
+	maxSize  int64
+	expiry   time.Duration
+	products *ProductService
+	quotas   *UploadQuotaService
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewUploadService(dir string, maxSize int64, expiry time.Duration, products *ProductService, quotas *UploadQuotaService, logger *slog.Logger) *UploadService {
+	return &UploadService{
+		dir:      dir,
+		maxSize:  maxSize,
+		expiry:   expiry,
+		products: products,
+		quotas:   quotas,
+		logger:   logger,
+		locks:    make(map[string]*sync.Mutex),
+	}
+}
+
+func (s *UploadService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Create starts an upload of length bytes. The declared length counts
+// towards the daily upload quota of the caller until the upload is aborted
+// or expires; the image size limit does not apply, MaxSize does.
+func (s *UploadService) Create(ctx context.Context, filename string, length int64) (*models.Upload, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("upload length must be greater than zero")
+	}
+	if length > s.maxSize {
+		return nil, ErrUploadTooLarge
+	}
+	if !utils.IsAllowedFileType(filename) {
+		return nil, fmt.Errorf("invalid file type. Only jpg, jpeg, png allowed")
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating upload directory: %v", err)
+	}
+	s.purgeExpired()
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := s.quotas.ReserveQuota(ctx, length)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	upload := &models.Upload{
+		ID:        id,
+		Filename:  filepath.Base(filename),
+		Length:    length,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.expiry),
+		Quota:     quota,
+	}
+
+	if err := os.WriteFile(s.dataPath(id), nil, 0644); err != nil {
+		s.releaseQuota(upload)
+		return nil, fmt.Errorf("error creating upload file: %v", err)
+	}
+	if err := s.writeInfo(upload); err != nil {
+		os.Remove(s.dataPath(id))
+		s.releaseQuota(upload)
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+func (s *UploadService) Get(id string) (*models.Upload, error) {
+	upload, err := s.readInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadNotFound
+	}
+
+	// The partial file is the source of truth for the offset, so bytes
+	// received before a dropped connection are kept.
+	info, err := os.Stat(s.dataPath(id))
+	if err != nil {
+		return nil, ErrUploadNotFound
+	}
+	upload.Offset = info.Size()
+
+	return upload, nil
+}
+
+// WriteChunk appends the chunk read from src at the given offset and returns
+// the upload with its new offset. The offset has to match what the server
+// already holds; clients resume by asking for the current offset first.
+func (s *UploadService) WriteChunk(id string, offset int64, src io.Reader) (*models.Upload, error) {
+	unlock := s.lock(id)
+	defer unlock()
+
+	upload, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return upload, ErrUploadOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening upload file: %v", err)
+	}
+	defer f.Close()
+
+	// Never accept more than the declared length
+	remaining := upload.Length - upload.Offset
+	written, copyErr := io.Copy(f, io.LimitReader(src, remaining))
+	upload.Offset += written
+
+	if copyErr != nil {
+		// Whatever arrived before the connection dropped is kept
+		s.logger.Warn("upload interrupted", "upload_id", id, "offset", upload.Offset, "error", copyErr)
+	}
+
+	return upload, nil
+}
+
+// Attach finalizes a complete upload: the file is stored through the image
+// pipeline, set as the product's image and the partial data is removed.
+func (s *UploadService) Attach(ctx context.Context, id string, productID int) (*models.Product, error) {
+	unlock := s.lock(id)
+	defer unlock()
+
+	upload, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !upload.Complete() {
+		return nil, ErrUploadIncomplete
+	}
+
+	product, err := s.products.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(s.dataPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("error opening upload file: %v", err)
+	}
+	// Storing the file and updating the product succeed or fail together
+	err = s.products.UpdateWithImage(ctx, product, f, strings.ToLower(filepath.Ext(upload.Filename)))
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	s.remove(id)
+	return product, nil
+}
+
+// Delete aborts an upload and gives its quota reservation back.
+func (s *UploadService) Delete(id string) error {
+	unlock := s.lock(id)
+	defer unlock()
+
+	upload, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	s.remove(id)
+	s.releaseQuota(upload)
+	return nil
+}
+
+func (s *UploadService) lock(id string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[id] = l
+	}
+	s.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+func (s *UploadService) remove(id string) {
+	os.Remove(s.dataPath(id))
+	os.Remove(s.infoPath(id))
+
+	s.mu.Lock()
+	delete(s.locks, id)
+	s.mu.Unlock()
+}
+
+// purgeExpired removes uploads that were abandoned before completion and
+// gives their quota reservations back.
+func (s *UploadService) purgeExpired() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if _, err := s.Get(id); errors.Is(err, ErrUploadNotFound) {
+			s.logger.Info("removing expired upload", "upload_id", id)
+			upload, _ := s.readInfo(id)
+			s.remove(id)
+			if upload != nil {
+				s.releaseQuota(upload)
+			}
+		}
+	}
+}
+
+// releaseQuota gives back the quota reservation of an upload whose bytes
+// were never stored. It outlives the request that aborted the upload.
+func (s *UploadService) releaseQuota(upload *models.Upload) {
+	if err := s.quotas.Release(context.Background(), upload.Quota); err != nil {
+		s.logger.Error("releasing upload quota failed", "upload_id", upload.ID, "error", err)
+	}
+}
+
+// readInfo reads the stored info of an upload, expired or not.
+func (s *UploadService) readInfo(id string) (*models.Upload, error) {
+	if !validUploadID(id) {
+		return nil, ErrUploadNotFound
+	}
+
+	data, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload info: %v", err)
+	}
+
+	var upload models.Upload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("error decoding upload info: %v", err)
+	}
+	return &upload, nil
+}
+
+func (s *UploadService) writeInfo(upload *models.Upload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.infoPath(upload.ID), data, 0644); err != nil {
+		return fmt.Errorf("error saving upload info: %v", err)
+	}
+	return nil
+}
+
+func (s *UploadService) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".part")
+}
+
+func (s *UploadService) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating upload id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validUploadID guards against path traversal through the id in the URL.
+func validUploadID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
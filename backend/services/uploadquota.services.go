@@ -0,0 +1,94 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrImageTooLarge       = errors.New("image exceeds maximum size")
+	ErrUploadQuotaExceeded = errors.New("daily upload quota exceeded")
+)
+
+// UploadQuotaService enforces the maximum image size and the daily upload
+// byte quota of each principal. A limit of 0 disables the check.
+type UploadQuotaService struct {
+	usage        models.UploadUsageRepository
+	maxImageSize int64
+	dailyQuota   int64
+}
+
+func NewUploadQuotaService(usage models.UploadUsageRepository, maxImageSize int64, dailyQuota int64) *UploadQuotaService {
+	return &UploadQuotaService{usage: usage, maxImageSize: maxImageSize, dailyQuota: dailyQuota}
+}
+
+func (s *UploadQuotaService) MaxImageSize() int64 {
+	return s.maxImageSize
+}
+
+// Reserve checks an image of size bytes against the limits and counts it
+// towards the quota of the caller. The bytes stay counted even when the
+// upload later fails.
+func (s *UploadQuotaService) Reserve(ctx context.Context, size int64) error {
+	if s.maxImageSize > 0 && size > s.maxImageSize {
+		return fmt.Errorf("%w: %d bytes, the limit is %d bytes", ErrImageTooLarge, size, s.maxImageSize)
+	}
+	_, err := s.ReserveQuota(ctx, size)
+	return err
+}
+
+// ReserveQuota counts size bytes towards the daily quota of the caller
+// without the image size limit, resumable uploads have their own. The
+// reservation is nil when there is no quota.
+func (s *UploadQuotaService) ReserveQuota(ctx context.Context, size int64) (*models.QuotaReservation, error) {
+	if s.dailyQuota <= 0 {
+		return nil, nil
+	}
+
+	reservation := &models.QuotaReservation{Subject: quotaSubject(ctx), Day: today(), Bytes: size}
+	ok, used, err := s.usage.Reserve(ctx, reservation.Subject, reservation.Day, size, s.dailyQuota)
+	if err != nil {
+		return nil, fmt.Errorf("error checking upload quota: %v", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %d of %d bytes used today", ErrUploadQuotaExceeded, used, s.dailyQuota)
+	}
+	return reservation, nil
+}
+
+// Release gives back a reservation of bytes that were never stored.
+func (s *UploadQuotaService) Release(ctx context.Context, reservation *models.QuotaReservation) error {
+	if reservation == nil {
+		return nil
+	}
+	return s.usage.Release(ctx, reservation.Subject, reservation.Day, reservation.Bytes)
+}
+
+// Usage returns what the caller uploaded today.
+func (s *UploadQuotaService) Usage(ctx context.Context) (*models.UploadUsage, error) {
+	subject, day := quotaSubject(ctx), today()
+	used, err := s.usage.Get(ctx, subject, day)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UploadUsage{Subject: subject, Day: day, Bytes: used, Limit: s.dailyQuota}, nil
+}
+
+// RetryAfter is the time until the quota resets at midnight UTC.
+func (s *UploadQuotaService) RetryAfter() time.Duration {
+	return time.Until(today().AddDate(0, 0, 1))
+}
+
+func quotaSubject(ctx context.Context) string {
+	if principal := models.PrincipalFromContext(ctx); principal != nil {
+		return principal.AuthMethod + ":" + principal.Subject
+	}
+	return "anonymous"
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,533 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"PRODUCT_LIST/metrics"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Request headers of webhook deliveries. Receivers verify the signature,
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription
+// secret, and reject old timestamps to prevent replays by third parties.
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderEventID   = "X-Webhook-Id"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// At most this much of a receiver's response is kept in the delivery log
+const maxWebhookResponseBody = 1024
+
+type WebhookOptions struct {
+	// Timeout bounds a delivery attempt
+	Timeout time.Duration
+	// AllowedNetworks are CIDRs receivers may be on although they are
+	// loopback, link-local or private, e.g. "127.0.0.1/32" for a local
+	// receiver. Everything else in those ranges is refused.
+	AllowedNetworks []string
+}
+
+// WebhookService manages webhook subscriptions and sends product events to
+// them. Events are queued as jobs with the change that caused them, so a
+// rolled back change never notifies anyone and a slow receiver never slows
+// down a request.
+type WebhookService struct {
+	repo    models.WebhookRepository
+	uow     models.UnitOfWork
+	jobs    *JobService
+	client  *http.Client
+	allowed []*net.IPNet
+	logger  *slog.Logger
+}
+
+func NewWebhookService(repo models.WebhookRepository, uow models.UnitOfWork, jobs *JobService, options WebhookOptions, logger *slog.Logger) (*WebhookService, error) {
+	s := &WebhookService{repo: repo, uow: uow, jobs: jobs, logger: logger}
+
+	for _, cidr := range options.AllowedNetworks {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook allowed network %q: %v", cidr, err)
+		}
+		s.allowed = append(s.allowed, network)
+	}
+
+	// Addresses are checked when connecting, after DNS resolution, so a
+	// name resolving to an internal address is refused as well. There is
+	// no proxy, it would connect on our behalf without the check
+	dialer := &net.Dialer{Timeout: options.Timeout, Control: s.checkDial}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	s.client = &http.Client{
+		Timeout:   options.Timeout,
+		Transport: transport,
+		// A redirect counts as a failed delivery, the signed payload is
+		// never sent to a URL nobody subscribed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return s, nil
+}
+
+// Ranges no receiver may be on unless allowed, on top of the loopback,
+// private and link-local ones known to net.IP
+var blockedWebhookNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+	mustParseCIDR("64:ff9b::/96"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// checkDial is the net.Dialer Control of deliveries, it refuses connections
+// to internal addresses so that webhooks cannot be used to reach services
+// behind the firewall.
+func (s *WebhookService) checkDial(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("webhook address %s is not an IP address", host)
+	}
+	if !s.addressAllowed(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", ip)
+	}
+	return nil
+}
+
+func (s *WebhookService) addressAllowed(ip net.IP) bool {
+	for _, network := range s.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range blockedWebhookNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *WebhookService) Create(ctx context.Context, rawURL string, secret string, eventTypes []string, createdBy string) (*models.CreatedWebhookSubscription, error) {
+	if err := s.validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	if err := validateEventTypes(eventTypes); err != nil {
+		return nil, err
+	}
+	secret, err := webhookSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:        rawURL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		Active:     true,
+		CreatedBy:  createdBy,
+	}
+	if err := s.repo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "webhook subscription created", "subscription_id", subscription.ID, "url", subscription.URL)
+
+	return &models.CreatedWebhookSubscription{WebhookSubscription: *subscription, Secret: secret}, nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *WebhookService) Get(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	return s.repo.GetSubscription(ctx, id)
+}
+
+// WebhookUpdate holds the subscription fields to change, nil fields are
+// kept.
+type WebhookUpdate struct {
+	URL        *string
+	Secret     *string
+	EventTypes []string
+	Active     *bool
+}
+
+func (s *WebhookService) Update(ctx context.Context, id int, update WebhookUpdate) (*models.WebhookSubscription, error) {
+	subscription, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.URL != nil {
+		if err := s.validateURL(*update.URL); err != nil {
+			return nil, err
+		}
+		subscription.URL = *update.URL
+	}
+	if update.EventTypes != nil {
+		if err := validateEventTypes(update.EventTypes); err != nil {
+			return nil, err
+		}
+		subscription.EventTypes = update.EventTypes
+	}
+	if update.Secret != nil {
+		if *update.Secret == "" {
+			return nil, fmt.Errorf("secret cannot be empty")
+		}
+		if subscription.Secret, err = webhookSecret(*update.Secret); err != nil {
+			return nil, err
+		}
+	}
+	if update.Active != nil {
+		subscription.Active = *update.Active
+	}
+
+	if err := s.repo.UpdateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "webhook subscription updated", "subscription_id", id)
+	return subscription, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id int) error {
+	if err := s.repo.DeleteSubscription(ctx, id); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "webhook subscription deleted", "subscription_id", id)
+	return nil
+}
+
+func (s *WebhookService) Deliveries(ctx context.Context, filter models.WebhookDeliveryFilter) (*models.WebhookDeliveryPage, error) {
+	if _, err := s.repo.GetSubscription(ctx, filter.SubscriptionID); err != nil {
+		return nil, err
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	return s.repo.ListDeliveries(ctx, filter)
+}
+
+func (s *WebhookService) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	return s.repo.GetDelivery(ctx, id)
+}
+
+// Replay sends a failed delivery again, with the original event id and
+// payload so that receivers can tell it apart from a new event.
+func (s *WebhookService) Replay(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.ResetDelivery(ctx, id); err != nil {
+			return err
+		}
+		queued, err := s.jobs.EnqueueUnique(ctx, models.JobTypeWebhookDeliver, webhookDeliveryKey(id), models.WebhookDeliveryJobPayload{DeliveryID: id})
+		if err != nil {
+			return err
+		}
+		if !queued {
+			return fmt.Errorf("webhook delivery %d is already queued", id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "webhook delivery replayed", "delivery_id", id)
+	return s.repo.GetDelivery(ctx, id)
+}
+
+// Ping sends a ping event to the subscription right away and returns the
+// logged delivery, to check a receiver and its signature verification.
+// Pings are not retried.
+func (s *WebhookService) Ping(ctx context.Context, id int) (*models.WebhookDelivery, error) {
+	subscription, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := newWebhookEvent(models.EventPing, map[string]int{"subscriptionId": id})
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := s.createDelivery(ctx, subscription.ID, event)
+	if err != nil {
+		return nil, err
+	}
+
+	// The outcome is recorded in the delivery either way
+	s.deliver(ctx, subscription, delivery)
+	return delivery, nil
+}
+
+// Publish queues an event for the subscribers of eventType. Called with the
+// context of a unit of work, the event is only sent once it commits.
+func (s *WebhookService) Publish(ctx context.Context, eventType string, data interface{}) error {
+	event, err := newWebhookEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+	_, err = s.jobs.Enqueue(ctx, models.JobTypeWebhookDispatch, event)
+	return err
+}
+
+// DispatchJob is the JobHandler of webhook.dispatch jobs: it records a
+// delivery for every active subscription to the event and queues sending
+// it. Deliveries recorded by an earlier attempt of the job are skipped.
+func (s *WebhookService) DispatchJob(ctx context.Context, payload json.RawMessage) error {
+	var event models.WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("invalid webhook event: %v", err)
+	}
+
+	subscriptions, err := s.repo.SubscriptionsFor(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		err := s.uow.Do(ctx, func(ctx context.Context) error {
+			delivery, err := s.createDelivery(ctx, subscription.ID, &event)
+			if err != nil || delivery == nil {
+				return err
+			}
+			_, err = s.jobs.EnqueueUnique(ctx, models.JobTypeWebhookDeliver, webhookDeliveryKey(delivery.ID), models.WebhookDeliveryJobPayload{DeliveryID: delivery.ID})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverJob is the JobHandler of webhook.deliver jobs. A failed delivery
+// returns an error, so the job is retried with exponential backoff.
+func (s *WebhookService) DeliverJob(ctx context.Context, payload json.RawMessage) error {
+	var p models.WebhookDeliveryJobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid job payload: %v", err)
+	}
+
+	delivery, err := s.repo.GetDelivery(ctx, p.DeliveryID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			// The subscription was deleted with its deliveries
+			s.logger.InfoContext(ctx, "skipping delivery of deleted webhook", "delivery_id", p.DeliveryID)
+			return nil
+		}
+		return err
+	}
+
+	subscription, err := s.repo.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	if !subscription.Active {
+		s.logger.InfoContext(ctx, "skipping delivery of disabled webhook", "delivery_id", delivery.ID, "subscription_id", subscription.ID)
+		return nil
+	}
+
+	return s.deliver(ctx, subscription, delivery)
+}
+
+// createDelivery records event for a subscription, nil when it was already
+// recorded.
+func (s *WebhookService) createDelivery(ctx context.Context, subscriptionID int, event *models.WebhookEvent) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding webhook event: %v", err)
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Payload:        body,
+	}
+	created, err := s.repo.CreateDelivery(ctx, delivery)
+	if err != nil || !created {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// deliver POSTs the delivery payload to the subscription and records the
+// attempt. Any response other than 2xx is a failure.
+func (s *WebhookService) deliver(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	start := time.Now()
+	status, body, sendErr := s.send(ctx, subscription, delivery)
+	duration := int(time.Since(start).Milliseconds())
+
+	delivery.DurationMs = &duration
+	delivery.ResponseStatus = nil
+	delivery.ResponseBody = body
+	delivery.LastError = ""
+	delivery.DeliveredAt = nil
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	if sendErr == nil && (status < 200 || status > 299) {
+		sendErr = fmt.Errorf("receiver responded with status %d", status)
+	}
+	if sendErr != nil {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = sendErr.Error()
+	} else {
+		now := time.Now()
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	}
+	metrics.WebhookDeliveries.WithLabelValues(delivery.EventType, delivery.Status).Inc()
+
+	// Recording the attempt must not fail because the job used up its time
+	if err := s.repo.RecordAttempt(context.WithoutCancel(ctx), delivery); err != nil {
+		s.logger.ErrorContext(ctx, "recording webhook delivery failed", "delivery_id", delivery.ID, "error", err)
+	}
+
+	if sendErr != nil {
+		s.logger.WarnContext(ctx, "webhook delivery failed", "delivery_id", delivery.ID, "subscription_id", subscription.ID, "event_type", delivery.EventType, "error", sendErr)
+		return sendErr
+	}
+	s.logger.InfoContext(ctx, "webhook delivered", "delivery_id", delivery.ID, "subscription_id", subscription.ID, "event_type", delivery.EventType, "duration_ms", duration)
+	return nil
+}
+
+func (s *WebhookService) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (status int, body string, err error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PRODUCT_LIST-Webhooks/1.0")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderEventID, delivery.EventID)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	// Postgres text holds neither invalid UTF-8 nor NUL bytes
+	body = strings.ReplaceAll(strings.ToValidUTF8(string(data), ""), "\x00", "")
+	return resp.StatusCode, body, nil
+}
+
+// SignWebhook returns the X-Webhook-Signature value of a payload sent at
+// timestamp.
+func SignWebhook(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookEvent(eventType string, data interface{}) (*models.WebhookEvent, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding webhook event: %v", err)
+	}
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebhookEvent{
+		ID:        "evt_" + id,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      encoded,
+	}, nil
+}
+
+func webhookDeliveryKey(id int64) string {
+	return fmt.Sprintf("webhook.delivery:%d", id)
+}
+
+// validateURL rejects malformed URLs and internal IP addresses early, host
+// names are checked when a delivery connects.
+func (s *WebhookService) validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid url, an absolute http or https URL is required")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !s.addressAllowed(ip) {
+		return fmt.Errorf("invalid url, address %s is not allowed", ip)
+	}
+	return nil
+}
+
+func validateEventTypes(eventTypes []string) error {
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	for _, eventType := range eventTypes {
+		if !models.IsValidEventType(eventType) {
+			return fmt.Errorf("invalid event type %q", eventType)
+		}
+	}
+	return nil
+}
+
+// webhookSecret returns secret, or a random one when it is empty.
+func webhookSecret(secret string) (string, error) {
+	if secret == "" {
+		token, err := randomToken(32)
+		if err != nil {
+			return "", err
+		}
+		return "whsec_" + token, nil
+	}
+	if len(secret) < 16 {
+		return "", fmt.Errorf("secret must be at least 16 characters")
+	}
+	return secret, nil
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"PRODUCT_LIST/domain/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// memoryWebhookRepository keeps subscriptions and deliveries in memory.
+type memoryWebhookRepository struct {
+	mu            sync.Mutex
+	subscriptions map[int]*models.WebhookSubscription
+	deliveries    map[int64]*models.WebhookDelivery
+	attempts      []models.WebhookDelivery
+}
+
+func newMemoryWebhookRepository(subscriptions ...models.WebhookSubscription) *memoryWebhookRepository {
+	repo := &memoryWebhookRepository{
+		subscriptions: make(map[int]*models.WebhookSubscription),
+		deliveries:    make(map[int64]*models.WebhookDelivery),
+	}
+	for i := range subscriptions {
+		repo.subscriptions[subscriptions[i].ID] = &subscriptions[i]
+	}
+	return repo
+}
+
+func (r *memoryWebhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription.ID = len(r.subscriptions) + 1
+	r.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (r *memoryWebhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscriptions := []models.WebhookSubscription{}
+	for _, subscription := range r.subscriptions {
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, nil
+}
+
+func (r *memoryWebhookRepository) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, fmt.Errorf("webhook not found")
+	}
+	copied := *subscription
+	return &copied, nil
+}
+
+func (r *memoryWebhookRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (r *memoryWebhookRepository) DeleteSubscription(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subscriptions, id)
+	return nil
+}
+
+func (r *memoryWebhookRepository) SubscriptionsFor(ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	return r.ListSubscriptions(ctx)
+}
+
+func (r *memoryWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.ID = int64(len(r.deliveries) + 1)
+	delivery.Status = models.WebhookDeliveryPending
+	r.deliveries[delivery.ID] = delivery
+	return true, nil
+}
+
+func (r *memoryWebhookRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, fmt.Errorf("delivery not found")
+	}
+	copied := *delivery
+	return &copied, nil
+}
+
+func (r *memoryWebhookRepository) ListDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) (*models.WebhookDeliveryPage, error) {
+	return &models.WebhookDeliveryPage{}, nil
+}
+
+func (r *memoryWebhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, *delivery)
+	return nil
+}
+
+func (r *memoryWebhookRepository) ResetDelivery(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (r *memoryWebhookRepository) recorded(t *testing.T) []models.WebhookDelivery {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.WebhookDelivery(nil), r.attempts...)
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newTestWebhookService allows loopback receivers, where httptest listens.
+func newTestWebhookService(t *testing.T, repo models.WebhookRepository) *WebhookService {
+	t.Helper()
+	service, err := NewWebhookService(repo, nil, nil, WebhookOptions{AllowedNetworks: []string{"127.0.0.0/8", "::1/128"}}, discardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestSignWebhook(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"product.created"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(payload)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignWebhook("secret", "1700000000", payload); got != want {
+		t.Fatalf("SignWebhook = %q, want %q", got, want)
+	}
+	if SignWebhook("other", "1700000000", payload) == want {
+		t.Error("signature does not depend on the secret")
+	}
+	if SignWebhook("secret", "1700000001", payload) == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestPingSendsSignedEvent(t *testing.T) {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		body, _ := io.ReadAll(r.Body)
+
+		timestamp := r.Header.Get(WebhookHeaderTimestamp)
+		if got, want := r.Header.Get(WebhookHeaderSignature), SignWebhook("s3cret-value", timestamp, body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if got := r.Header.Get(WebhookHeaderEvent); got != models.EventPing {
+			t.Errorf("event header = %q, want %q", got, models.EventPing)
+		}
+
+		var event models.WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("invalid event body: %v", err)
+		}
+		if event.ID == "" || event.ID != r.Header.Get(WebhookHeaderEventID) {
+			t.Errorf("event id %q does not match header %q", event.ID, r.Header.Get(WebhookHeaderEventID))
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer receiver.Close()
+
+	repo := newMemoryWebhookRepository(models.WebhookSubscription{ID: 1, URL: receiver.URL, Secret: "s3cret-value", Active: true})
+	service := newTestWebhookService(t, repo)
+
+	delivery, err := service.Ping(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.Load() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", received.Load())
+	}
+	if delivery.Status != models.WebhookDeliverySucceeded {
+		t.Errorf("status = %q, want %q (error %q)", delivery.Status, models.WebhookDeliverySucceeded, delivery.LastError)
+	}
+	if delivery.ResponseStatus == nil || *delivery.ResponseStatus != http.StatusOK || delivery.ResponseBody != "ok" {
+		t.Errorf("response not recorded: %v %q", delivery.ResponseStatus, delivery.ResponseBody)
+	}
+	if attempts := repo.recorded(t); len(attempts) != 1 || attempts[0].Status != models.WebhookDeliverySucceeded {
+		t.Errorf("recorded attempts = %+v", attempts)
+	}
+}
+
+func TestDeliverJobRecordsFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		closed     bool
+		wantStatus int
+	}{
+		{
+			name:       "server error",
+			handler:    func(w http.ResponseWriter, r *http.Request) { http.Error(w, "boom", http.StatusInternalServerError) },
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "redirect",
+			handler:    func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/elsewhere", http.StatusFound) },
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:    "connection refused",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			closed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := httptest.NewServer(tt.handler)
+			if tt.closed {
+				receiver.Close()
+			} else {
+				defer receiver.Close()
+			}
+
+			repo := newMemoryWebhookRepository(models.WebhookSubscription{ID: 1, URL: receiver.URL, Secret: "s3cret-value", Active: true})
+			repo.CreateDelivery(context.Background(), &models.WebhookDelivery{SubscriptionID: 1, EventID: "evt_1", EventType: models.EventProductCreated, Payload: []byte(`{}`)})
+			service := newTestWebhookService(t, repo)
+
+			payload, _ := json.Marshal(models.WebhookDeliveryJobPayload{DeliveryID: 1})
+			if err := service.DeliverJob(context.Background(), payload); err == nil {
+				t.Fatal("DeliverJob succeeded, want an error so the job is retried")
+			}
+
+			attempts := repo.recorded(t)
+			if len(attempts) != 1 {
+				t.Fatalf("recorded %d attempts, want 1", len(attempts))
+			}
+			attempt := attempts[0]
+			if attempt.Status != models.WebhookDeliveryFailed || attempt.LastError == "" {
+				t.Errorf("attempt = %q with error %q, want a failure", attempt.Status, attempt.LastError)
+			}
+			switch {
+			case tt.wantStatus == 0 && attempt.ResponseStatus != nil:
+				t.Errorf("response status = %d, want none", *attempt.ResponseStatus)
+			case tt.wantStatus != 0 && (attempt.ResponseStatus == nil || *attempt.ResponseStatus != tt.wantStatus):
+				t.Errorf("response status = %v, want %d", attempt.ResponseStatus, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDeliverJobSkipsInactiveSubscriptions(t *testing.T) {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer receiver.Close()
+
+	repo := newMemoryWebhookRepository(models.WebhookSubscription{ID: 1, URL: receiver.URL, Secret: "s3cret-value", Active: false})
+	repo.CreateDelivery(context.Background(), &models.WebhookDelivery{SubscriptionID: 1, EventID: "evt_1", EventType: models.EventProductCreated, Payload: []byte(`{}`)})
+	service := newTestWebhookService(t, repo)
+
+	payload, _ := json.Marshal(models.WebhookDeliveryJobPayload{DeliveryID: 1})
+	if err := service.DeliverJob(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if received.Load() != 0 || len(repo.recorded(t)) != 0 {
+		t.Error("delivery of a disabled webhook was sent")
+	}
+}
+
+func TestDeliverRefusesInternalAddresses(t *testing.T) {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer receiver.Close()
+
+	repo := newMemoryWebhookRepository(models.WebhookSubscription{ID: 1, URL: receiver.URL, Secret: "s3cret-value", Active: true})
+	service, err := NewWebhookService(repo, nil, nil, WebhookOptions{}, discardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery, err := service.Ping(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.Load() != 0 {
+		t.Fatal("receiver on a loopback address was reached")
+	}
+	if delivery.Status != models.WebhookDeliveryFailed || !strings.Contains(delivery.LastError, "not allowed") {
+		t.Errorf("delivery = %q with error %q, want refused", delivery.Status, delivery.LastError)
+	}
+}
+
+func TestNewWebhookServiceRejectsInvalidNetworks(t *testing.T) {
+	_, err := NewWebhookService(newMemoryWebhookRepository(), nil, nil, WebhookOptions{AllowedNetworks: []string{"10.0.0.0"}}, discardLogger())
+	if err == nil {
+		t.Fatal("want an error for a network without prefix length")
+	}
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader serves a TLS certificate from disk and picks up a new one
+// when the files change, so certificates rotated by cert-manager or certbot
+// are used without a restart.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func NewCertReloader(certFile string, keyFile string, logger *slog.Logger) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the key pair. On error the previous certificate is kept.
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading TLS certificate: %v", err)
+	}
+
+	modified, _ := r.modTime()
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modified = modified
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate is meant for tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch checks the files every interval and reloads them when they changed,
+// until the returned stop function is called. An interval of zero or less
+// disables reloading.
+func (r *CertReloader) Watch(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				modified, err := r.modTime()
+				if err != nil {
+					r.logger.Error("checking TLS certificate failed", "error", err)
+					continue
+				}
+
+				r.mu.RLock()
+				changed := !modified.Equal(r.modified)
+				r.mu.RUnlock()
+				if !changed {
+					continue
+				}
+
+				if err := r.Reload(); err != nil {
+					// The pair may be half written, try again next tick
+					r.logger.Error("reloading TLS certificate failed", "error", err)
+					continue
+				}
+				r.logger.Info("reloaded TLS certificate", "file", r.certFile)
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// modTime is the latest modification time of the certificate and key.
+// os.Stat follows symlinks, so Kubernetes secret volumes swapping their
+// ..data link are noticed too.
+func (r *CertReloader) modTime() (time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if keyInfo.ModTime().After(certInfo.ModTime()) {
+		return keyInfo.ModTime(), nil
+	}
+	return certInfo.ModTime(), nil
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// DiskCache is a size bounded least-recently-used cache of files on disk.
+// Keys are used as filenames and must not contain path separators.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List // front is the most recently used entry
+	entries map[string]*list.Element
+}
+
+type diskCacheEntry struct {
+	key  string
+	size int64
+}
+
+// NewDiskCache opens the cache directory and indexes the files already in
+// it, oldest first, so a restart keeps the warm cache.
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %v", err)
+	}
+
+	c := &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache directory: %v", err)
+	}
+
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	for _, info := range files {
+		c.entries[info.Name()] = c.order.PushFront(&diskCacheEntry{key: info.Name(), size: info.Size()})
+		c.size += info.Size()
+	}
+	c.evict()
+
+	return c, nil
+}
+
+// Get opens the cached file for key, the caller closes it. The file is
+// opened under the lock eviction takes, an evicted entry stays readable
+// through it until it is closed.
+func (c *DiskCache) Get(key string) (*os.File, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	file, err := os.Open(filepath.Join(c.dir, key))
+	if err != nil {
+		// Removed behind the cache's back, forget it
+		c.size -= elem.Value.(*diskCacheEntry).size
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return file, true
+}
+
+// Put stores data under key and evicts the least recently used files until
+// the cache fits in its size bound again.
+func (c *DiskCache) Put(key string, data []byte) error {
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, key)); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*diskCacheEntry).size
+		c.order.Remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&diskCacheEntry{key: key, size: int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+
+	return nil
+}
+
+// evict must be called with mu held.
+func (c *DiskCache) evict() {
+	for c.size > c.maxBytes && c.order.Len() > 1 {
+		elem := c.order.Back()
+		entry := elem.Value.(*diskCacheEntry)
+
+		os.Remove(filepath.Join(c.dir, entry.key))
+		c.order.Remove(elem)
+		delete(c.entries, entry.key)
+		c.size -= entry.size
+	}
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir(), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "b"} {
+		if err := cache.Put(key, []byte("1234")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reading a makes b the least recently used entry
+	if file, ok := cache.Get("a"); !ok {
+		t.Fatal("a missing")
+	} else {
+		file.Close()
+	}
+	if err := cache.Put("c", []byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if file, ok := cache.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		} else {
+			file.Close()
+		}
+	}
+}
+
+func TestDiskCacheGetSurvivesEviction(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Put("a", []byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+
+	file, ok := cache.Get("a")
+	if !ok {
+		t.Fatal("a missing")
+	}
+	defer file.Close()
+
+	// Evicts a while it is being served
+	if err := cache.Put("b", []byte("5678")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "1234" {
+		t.Errorf("read %q from an evicted entry, want %q", data, "1234")
+	}
+}
+
+func TestDiskCacheGetForgetsRemovedFiles(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Put("a", []byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("removed file reported as cached")
+	}
+	if cache.size != 0 {
+		t.Errorf("size = %d after forgetting the entry, want 0", cache.size)
+	}
+}
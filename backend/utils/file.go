@@ -1,50 +1,125 @@
 package utils
 
 import (
+	"PRODUCT_LIST/metrics"
+	"PRODUCT_LIST/tracing"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Capitalized = Public/Exported
 // Lowercase = Private/Unexported
+
+// UploadDir is where product images are stored and served from.
+const UploadDir = "uploads"
+
 // Helper functions
 func IsAllowedFileType(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	return ext == ".jpg" || ext == ".jpeg" || ext == ".png"
 }
 
-func HandleFileUpload(file multipart.File, handler *multipart.FileHeader) (string, error) {
-	// Check file type
-	if !IsAllowedFileType(handler.Filename) {
-		return "", fmt.Errorf("invalid file type. Only jpg, jpeg, png allowed")
-	}
+// SaveImage writes the image read from src into the uploads directory and
+// returns its public URL. Every upload path (multipart forms, base64 and
+// resumable uploads) ends up here.
+//
+// Files are content addressed: the name is the SHA-256 of the content, so
+// the same image uploaded for several products is stored only once.
+func SaveImage(ctx context.Context, src io.Reader, ext string) (string, error) {
+	imageURL, _, err := StoreImage(ctx, src, ext)
+	return imageURL, err
+}
 
-	// Generate unique filename
-	filename := fmt.Sprintf("product-%d%s",
-		time.Now().UnixNano(),
-		filepath.Ext(handler.Filename))
+// StoreImage is SaveImage, created reports whether the file was written by
+// this call rather than already stored.
+func StoreImage(ctx context.Context, src io.Reader, ext string) (imageURL string, created bool, err error) {
+	_, span := tracing.Start(ctx, "SaveImage")
+	defer func() { tracing.End(span, err) }()
 
 	// Create uploads directory if it doesn't exist, else won't register in database as string.
-	if err := os.MkdirAll("uploads", 0755); err != nil {
-		return "", fmt.Errorf("error creating upload directory: %v", err)
+	if err := os.MkdirAll(UploadDir, 0755); err != nil {
+		return "", false, fmt.Errorf("error creating upload directory: %v", err)
 	}
 
-	// Create the file
-	dst, err := os.Create(filepath.Join("uploads", filename))
+	// Write to a temporary file first, the final name is only known once
+	// the whole content has been hashed
+	tmp, err := os.CreateTemp(UploadDir, ".upload-*")
 	if err != nil {
-		return "", fmt.Errorf("error creating file: %v", err)
+		return "", false, fmt.Errorf("error creating file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), src)
+	if err != nil {
+		tmp.Close()
+		return "", false, fmt.Errorf("error saving file: %v", err)
+	}
+	metrics.UploadSize.Observe(float64(size))
+	span.SetAttributes(attribute.Int64("image.size", size))
+	if err := tmp.Close(); err != nil {
+		return "", false, fmt.Errorf("error saving file: %v", err)
+	}
+
+	filename := hex.EncodeToString(hash.Sum(nil)) + strings.ToLower(ext)
+	dst := filepath.Join(UploadDir, filename)
+
+	// Identical content is already stored, keep the existing file. It may
+	// be an old orphan, touching it restarts the GC grace period so that it
+	// is not collected before the new reference commits
+	if _, err := os.Stat(dst); err == nil {
+		now := time.Now()
+		if err := os.Chtimes(dst, now, now); err != nil {
+			return "", false, fmt.Errorf("error saving file: %v", err)
+		}
+		return "/uploads/" + filename, false, nil
 	}
-	defer dst.Close()
 
-	// Copy the uploaded file
-	if _, err := io.Copy(dst, file); err != nil {
-		return "", fmt.Errorf("error saving file: %v", err)
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", false, fmt.Errorf("error saving file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", false, fmt.Errorf("error saving file: %v", err)
 	}
 
-	return "/uploads/" + filename, nil
+	return "/uploads/" + filename, true, nil
+}
+
+// RemoveImage deletes the stored file behind an image URL.
+func RemoveImage(imageURL string) error {
+	filename := filepath.Base(imageURL)
+	if !strings.HasPrefix(imageURL, "/uploads/") || filename == "." || filename == "/" {
+		return fmt.Errorf("not an uploaded image: %s", imageURL)
+	}
+	return os.Remove(filepath.Join(UploadDir, filename))
+}
+
+// ContentAddressedName returns the filename SaveImage would use for the
+// content read from src, without storing anything.
+func ContentAddressedName(src io.Reader, ext string) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, src); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)) + strings.ToLower(ext), nil
+}
+
+// IsContentAddressed reports whether filename follows the
+// "<sha256><ext>" naming used by SaveImage. Such files never change.
+func IsContentAddressed(filename string) bool {
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
 }
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// signedQuery signs path and returns the expires and signature parameters.
+func signedQuery(t *testing.T, signer *URLSigner, path string) (string, string) {
+	t.Helper()
+	signed, err := url.Parse(signer.Sign(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Path != path {
+		t.Fatalf("signed path = %q, want %q", signed.Path, path)
+	}
+	return signed.Query().Get("expires"), signed.Query().Get("signature")
+}
+
+func TestURLSignerVerify(t *testing.T) {
+	signer := NewURLSigner("test-key", time.Minute, discardLogger())
+	expires, signature := signedQuery(t, signer, "/uploads/abc.png")
+
+	if !signer.Verify("/uploads/abc.png", expires, signature) {
+		t.Fatal("signed URL does not verify")
+	}
+
+	later, _ := strconv.ParseInt(expires, 10, 64)
+	tests := []struct {
+		name      string
+		path      string
+		expires   string
+		signature string
+	}{
+		{"other path", "/uploads/other.png", expires, signature},
+		{"extended expiry", "/uploads/abc.png", strconv.FormatInt(later+3600, 10), signature},
+		{"tampered signature", "/uploads/abc.png", expires, strings.Repeat("0", len(signature))},
+		{"missing signature", "/uploads/abc.png", expires, ""},
+		{"missing expiry", "/uploads/abc.png", "", signature},
+		{"invalid expiry", "/uploads/abc.png", "tomorrow", signature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if signer.Verify(tt.path, tt.expires, tt.signature) {
+				t.Error("verified")
+			}
+		})
+	}
+}
+
+func TestURLSignerVerifyRejectsExpired(t *testing.T) {
+	signer := NewURLSigner("test-key", -time.Second, discardLogger())
+	expires, signature := signedQuery(t, signer, "/uploads/abc.png")
+
+	if signer.Verify("/uploads/abc.png", expires, signature) {
+		t.Fatal("expired URL verified")
+	}
+}
+
+func TestURLSignerKeys(t *testing.T) {
+	signer := NewURLSigner("test-key", time.Minute, discardLogger())
+	expires, signature := signedQuery(t, signer, "/uploads/abc.png")
+
+	if !NewURLSigner("test-key", time.Hour, discardLogger()).Verify("/uploads/abc.png", expires, signature) {
+		t.Error("a signer with the same key rejects the URL, instances would not share signed URLs")
+	}
+	if NewURLSigner("other-key", time.Minute, discardLogger()).Verify("/uploads/abc.png", expires, signature) {
+		t.Error("a signer with another key accepts the URL")
+	}
+	if NewURLSigner("", time.Minute, discardLogger()).Verify("/uploads/abc.png", expires, signature) {
+		t.Error("a signer with a random key accepts the URL")
+	}
+}
+
+func TestURLSignerSignEmptyPath(t *testing.T) {
+	if got := NewURLSigner("test-key", time.Minute, discardLogger()).Sign(""); got != "" {
+		t.Errorf("Sign(\"\") = %q, want empty", got)
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AccessClaims are the claims carried by access tokens.
+type AccessClaims struct {
+	Role string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type AccessTokenOptions struct {
+	Secret   string
+	Issuer   string
+	Audience string
+	TTL      time.Duration
+}
+
+// IssueAccessToken signs a short-lived HS256 access token for subject.
+func IssueAccessToken(options AccessTokenOptions, subject string, role string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(options.TTL)
+
+	claims := AccessClaims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    options.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	if options.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{options.Audience}
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(options.Secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}